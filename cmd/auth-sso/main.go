@@ -24,14 +24,7 @@ func main() {
 	log := setupLogger(cfg.Env)
 	log.Info("Starting application", slog.String("env", cfg.Env))
 
-	application := app.New(
-		log,
-		cfg.GRPC.Port,
-		cfg.Database.Uri,
-		cfg.Database.DatabaseName,
-		cfg.TokenTTL,
-		cfg.Redis.Address,
-	)
+	application := app.New(log, cfg)
 	go application.GRPCServer.MustRun()
 
 	stop := make(chan os.Signal, 1)
@@ -42,7 +35,7 @@ func main() {
 	asynqServer := asynq.NewServer(redisClient, asynq.Config{Concurrency: 10})
 
 	mux := asynq.NewServeMux()
-	tasks.SetupTaskHandlers(mux)
+	tasks.SetupTaskHandlers(mux, application.Keys, application.Mailer)
 
 	go func() {
 		if err := asynqServer.Run(mux); err != nil {
@@ -50,9 +43,24 @@ func main() {
 		}
 	}()
 
+	asynqScheduler := asynq.NewScheduler(redisClient, nil)
+
+	if err := tasks.SetupScheduledTasks(asynqScheduler, cfg.JWT.RotationSchedule); err != nil {
+		panic("Failed to register scheduled tasks: " + err.Error())
+	}
+
+	if err := asynqScheduler.Start(); err != nil {
+		panic("Failed to start Asynq scheduler: " + err.Error())
+	}
+
 	receivedSignal := <-stop
 	log.Info("Stopping application", slog.String("signal", receivedSignal.String()))
 
+	application.GRPCServer.Stop()
+
+	asynqScheduler.Shutdown()
+	log.Info("Asynq scheduler shut down")
+
 	asynqServer.Shutdown()
 	log.Info("Asynq server shut down")
 
@@ -62,7 +70,17 @@ func main() {
 
 	log.Info("MongoDB connection is closed.")
 
-	application.GRPCServer.Stop()
+	if err := application.Cache.Close(); err != nil {
+		log.Error("Failed to close the Redis connection", slog.String("error", err.Error()))
+	}
+
+	log.Info("Redis connection is closed.")
+
+	if application.BreachCorpus != nil {
+		if err := application.BreachCorpus.Close(); err != nil {
+			log.Error("Failed to close the breached passwords corpus", slog.String("error", err.Error()))
+		}
+	}
 
 	if err := application.AsynqClient.Close(); err != nil {
 		log.Error("Failed to close the Asynq client", slog.String("error", err.Error()))
@@ -2,9 +2,20 @@ package app
 
 import (
 	grpcapp "auth-sso/internal/app/grpc"
+	"auth-sso/internal/config"
 	"auth-sso/internal/services/auth"
 	"auth-sso/internal/services/identity"
+	"auth-sso/internal/services/keys"
+	"auth-sso/internal/services/relations"
 	"auth-sso/internal/storage/mongodb"
+	"auth-sso/internal/storage/policyfile"
+	"auth-sso/internal/storage/redis"
+	"auth-sso/lib/breach"
+	"auth-sso/lib/encryption"
+	"auth-sso/lib/hasher"
+	"auth-sso/lib/mail"
+	"auth-sso/lib/passwordpolicy"
+	"fmt"
 	"github.com/hibiken/asynq"
 	"log/slog"
 	"time"
@@ -13,34 +24,215 @@ import (
 type App struct {
 	GRPCServer  *grpcapp.App
 	Storage     *mongodb.Storage
+	Cache       *redis.Storage
 	AsynqClient *asynq.Client
+	Keys        *keys.Keys
+	Mailer      *mail.Sender
+	// BreachCorpus is nil unless the breached password check is enabled.
+	BreachCorpus *breach.Checker
 }
 
 func New(
 	log *slog.Logger,
-	grpcPort int,
-	databaseUri string,
-	database string,
-	tokenTTL time.Duration,
-	redisAddr string,
+	cfg *config.Config,
 ) *App {
-	client, err := mongodb.New(databaseUri, database)
+	client, err := mongodb.New(cfg.Database.Uri, cfg.Database.DatabaseName, cfg.Groups.MaxDepth)
 	if err != nil {
 		panic(err)
 	}
 
 	log.Info("MongoDB connection is successful.")
 
-	redisClient := asynq.RedisClientOpt{Addr: redisAddr}
+	cache, err := redis.New(cfg.Redis.Address)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("Redis connection is successful.")
+
+	encryptionKey, err := encryption.ParseKey(cfg.EncryptionKey)
+	if err != nil {
+		panic(err)
+	}
+
+	redisClient := asynq.RedisClientOpt{Addr: cfg.Redis.Address}
 	asynqClient := asynq.NewClient(redisClient)
 
-	authService := auth.New(log, client, client, client, client, tokenTTL)
+	keysService := keys.New(
+		log,
+		client,
+		client,
+		cfg.JWT.Algorithm,
+		encryptionKey,
+		cfg.JWT.RotationPeriod,
+		cfg.JWT.RotationOverlap,
+	)
+	var breachChecker auth.BreachChecker
+	var breachCorpus *breach.Checker
+
+	if cfg.BreachedPasswords.Enabled {
+		breachCorpus, err = breach.Open(cfg.BreachedPasswords.Path)
+		if err != nil {
+			panic(err)
+		}
+
+		breachChecker = breachCorpus
+
+		log.Info("Breached passwords corpus is loaded.")
+	}
+
+	passwordHasher, err := newPasswordHasher(cfg.PasswordHashing)
+	if err != nil {
+		panic(err)
+	}
+
+	policyProvider, err := newPolicyProvider(cfg.Policies, client)
+	if err != nil {
+		panic(err)
+	}
+
+	policyLocation, err := time.LoadLocation(cfg.Policies.Timezone)
+	if err != nil {
+		panic(err)
+	}
+
+	mailer := mail.New(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From)
+
+	authService := auth.New(
+		log,
+		asynqClient,
+		auth.Deps{
+			UserSaver:            client,
+			UserProvider:         client,
+			AppProvider:          client,
+			PermissionProvider:   client,
+			RoleSaver:            client,
+			RoleProvider:         client,
+			GroupSaver:           client,
+			GroupProvider:        client,
+			PolicyProvider:       policyProvider,
+			RefreshTokenSaver:    client,
+			RefreshTokenProvider: client,
+			TokenRevoker:         cache,
+			RevokedTokenProvider: cache,
+			KeyProvider:          keysService,
+			MFASaver:             client,
+			MFAChallengeSaver:    cache,
+			MFAChallengeProvider: cache,
+			AuditEventSaver:      client,
+			OneTimeTokenSaver:    client,
+			OneTimeTokenProvider: client,
+			LoginAttemptTracker:  cache,
+			RequestLimiter:       cache,
+			BreachChecker:        breachChecker,
+		},
+		auth.Config{
+			TokenTTL:             cfg.TokenTTL,
+			MaxTokenTTL:          cfg.MaxTokenTTL,
+			RefreshTokenTTL:      cfg.RefreshTokenTTL,
+			EncryptionKey:        encryptionKey,
+			MFAIssuer:            cfg.MFA.Issuer,
+			MFAChallengeTTL:      cfg.MFA.ChallengeTTL,
+			MFAMaxAttempts:       cfg.MFA.MaxAttempts,
+			RecoveryCodeCount:    cfg.MFA.RecoveryCodes,
+			PasswordResetTTL:     cfg.PasswordReset.TokenTTL,
+			PasswordResetURL:     cfg.PasswordReset.URL,
+			EmailVerificationTTL: cfg.EmailVerification.TokenTTL,
+			EmailVerificationURL: cfg.EmailVerification.URL,
+			EmailChangeTTL:       cfg.EmailChange.TokenTTL,
+			EmailChangeURL:       cfg.EmailChange.URL,
+			Lockout: auth.LockoutConfig{
+				Window:           cfg.Lockout.Window,
+				AccountThreshold: cfg.Lockout.AccountThreshold,
+				IPThreshold:      cfg.Lockout.IPThreshold,
+				BaseDelay:        cfg.Lockout.BaseDelay,
+				MaxDelay:         cfg.Lockout.MaxDelay,
+			},
+			PasswordPolicy: passwordpolicy.Policy{
+				MinLength:     cfg.PasswordPolicy.MinLength,
+				MaxLength:     cfg.PasswordPolicy.MaxLength,
+				RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+				RequireLower:  cfg.PasswordPolicy.RequireLower,
+				RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+				RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+				DisallowEmail: cfg.PasswordPolicy.DisallowEmail,
+				History:       cfg.PasswordPolicy.History,
+			},
+			BreachMinCount:             cfg.BreachedPasswords.MinCount,
+			PasswordHasher:             passwordHasher,
+			PolicyLocation:             policyLocation,
+			Issuer:                     cfg.JWT.Issuer,
+			Audience:                   cfg.JWT.Audience,
+			AuthorizationClaimsMaxSize: cfg.JWT.AuthorizationClaimsMaxSize,
+			VerificationResendLimit:    cfg.EmailVerification.ResendLimit,
+			VerificationResendWindow:   cfg.EmailVerification.ResendWindow,
+		},
+	)
+
+	relationsService, err := relations.New(log, client, client, relationNamespaces(cfg.Relations), cfg.Relations.MaxDepth)
+	if err != nil {
+		panic(err)
+	}
+
 	identityService := identity.New(log, asynqClient, client, client, client)
-	grpcApp := grpcapp.New(log, authService, identityService, grpcPort)
+	grpcApp := grpcapp.New(log, authService, relationsService, identityService, authService, cfg.GRPC.Port)
 
 	return &App{
-		GRPCServer:  grpcApp,
-		Storage:     client,
-		AsynqClient: asynqClient,
+		GRPCServer:   grpcApp,
+		Storage:      client,
+		Cache:        cache,
+		AsynqClient:  asynqClient,
+		Keys:         keysService,
+		Mailer:       mailer,
+		BreachCorpus: breachCorpus,
+	}
+}
+
+func newPolicyProvider(cfg config.PoliciesConfig, client *mongodb.Storage) (auth.PolicyProvider, error) {
+	switch cfg.Source {
+	case "mongodb":
+		return client, nil
+	case "file":
+		return policyfile.New(cfg.Path)
 	}
+
+	return nil, fmt.Errorf("unsupported policy source: %s", cfg.Source)
+}
+
+func relationNamespaces(cfg config.RelationsConfig) []relations.Namespace {
+	namespaces := make([]relations.Namespace, 0, len(cfg.Namespaces))
+
+	for _, namespace := range cfg.Namespaces {
+		relationList := make([]relations.Relation, 0, len(namespace.Relations))
+		for _, relation := range namespace.Relations {
+			relationList = append(relationList, relations.Relation{
+				Name:     relation.Name,
+				Includes: relation.Includes,
+			})
+		}
+
+		namespaces = append(namespaces, relations.Namespace{
+			Name:      namespace.Name,
+			Relations: relationList,
+		})
+	}
+
+	return namespaces
+}
+
+func newPasswordHasher(cfg config.PasswordHashingConfig) (hasher.Hasher, error) {
+	switch cfg.Algorithm {
+	case hasher.AlgorithmBcrypt:
+		return hasher.Bcrypt{Cost: cfg.BcryptCost}, nil
+	case hasher.AlgorithmArgon2id:
+		return hasher.Argon2id{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+			SaltLength:  cfg.Argon2SaltLength,
+			KeyLength:   cfg.Argon2KeyLength,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", hasher.ErrorUnsupportedAlgorithm, cfg.Algorithm)
 }
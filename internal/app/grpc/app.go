@@ -3,6 +3,7 @@ package grpcapp
 import (
 	"auth-sso/internal/grpc/auth"
 	"auth-sso/internal/grpc/identity"
+	"auth-sso/lib/grpcauth"
 	"fmt"
 	"google.golang.org/grpc"
 	"log/slog"
@@ -18,12 +19,19 @@ type App struct {
 func New(
 	log *slog.Logger,
 	authService authgrpc.Auth,
+	relationsService authgrpc.Relations,
 	identityVerificationService identitygrpc.Verification,
+	verifier grpcauth.Verifier,
 	port int,
 ) *App {
-	gRPCServer := grpc.NewServer()
+	requireAuth := grpcauth.RequireMethods(authgrpc.AuthenticatedMethods...)
 
-	authgrpc.Register(gRPCServer, log, authService)
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcauth.UnaryServerInterceptor(verifier, requireAuth)),
+		grpc.ChainStreamInterceptor(grpcauth.StreamServerInterceptor(verifier, requireAuth)),
+	)
+
+	authgrpc.Register(gRPCServer, log, authService, relationsService)
 	identitygrpc.Register(gRPCServer, log, identityVerificationService)
 
 	return &App{
@@ -8,11 +8,27 @@ import (
 )
 
 type Config struct {
-	Env      string        `yaml:"env" env-default:"local"`
-	TokenTTL time.Duration `yaml:"token_ttl" env-required:"true"`
-	Database DatabaseConfig
-	GRPC     GRPCConfig
-	Redis    RedisConfig
+	Env               string        `yaml:"env" env-default:"local"`
+	TokenTTL          time.Duration `yaml:"token_ttl" env-required:"true"`
+	MaxTokenTTL       time.Duration `yaml:"max_token_ttl" env-default:"24h"`
+	RefreshTokenTTL   time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	EncryptionKey     string        `yaml:"encryption_key" env:"ENCRYPTION_KEY" env-required:"true"`
+	Database          DatabaseConfig
+	GRPC              GRPCConfig
+	Redis             RedisConfig
+	JWT               JWTConfig
+	MFA               MFAConfig
+	Mail              MailConfig
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	EmailChange       EmailChangeConfig       `yaml:"email_change"`
+	Lockout           LockoutConfig
+	PasswordPolicy    PasswordPolicyConfig    `yaml:"password_policy"`
+	BreachedPasswords BreachedPasswordsConfig `yaml:"breached_passwords"`
+	PasswordHashing   PasswordHashingConfig   `yaml:"password_hashing"`
+	Policies          PoliciesConfig
+	Relations         RelationsConfig
+	Groups            GroupsConfig
 }
 
 type DatabaseConfig struct {
@@ -29,6 +45,111 @@ type RedisConfig struct {
 	Address string `yaml:"address" env-default:"127.0.0.1"`
 }
 
+type JWTConfig struct {
+	// Algorithm is one of HS512, RS256, ES256 or EdDSA.
+	Algorithm string `yaml:"algorithm" env-default:"HS512"`
+	// RotationPeriod is how long a signing key is used before it is replaced.
+	RotationPeriod time.Duration `yaml:"rotation_period" env-default:"2160h"`
+	// RotationOverlap is how long a replaced key still verifies tokens. Must be longer than TokenTTL and MaxTokenTTL.
+	RotationOverlap time.Duration `yaml:"rotation_overlap" env-default:"24h"`
+	// RotationSchedule is the cron spec of the task checking for keys due for rotation.
+	RotationSchedule string `yaml:"rotation_schedule" env-default:"@hourly"`
+	// Issuer is the iss claim of issued tokens, usually the URL of the service.
+	Issuer string `yaml:"issuer" env-default:"auth-sso"`
+	// Audience is the aud claim of tokens of apps which don't set their own audience.
+	Audience []string `yaml:"audience" env-default:"auth-sso"`
+	// AuthorizationClaimsMaxSize is the maximum size in bytes of the roles and permissions embedded in
+	// the tokens of apps which opted in, larger sets are replaced by their hash.
+	AuthorizationClaimsMaxSize int `yaml:"authorization_claims_max_size" env-default:"2048"`
+}
+
+type MFAConfig struct {
+	// Issuer is the account issuer shown in authenticator apps.
+	Issuer string `yaml:"issuer" env-default:"auth-sso"`
+	// ChallengeTTL is how long a login waits for the second factor.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	// MaxAttempts is the number of codes which can be tried for one login.
+	MaxAttempts int `yaml:"max_attempts" env-default:"5"`
+	// RecoveryCodes is the number of single-use recovery codes handed out on enrollment.
+	RecoveryCodes int `yaml:"recovery_codes" env-default:"10"`
+}
+
+type MailConfig struct {
+	Host     string `yaml:"host" env-default:"127.0.0.1"`
+	Port     int    `yaml:"port" env-default:"25"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env-default:"no-reply@auth-sso.local"`
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// URL is the page of the reset link, the reset token is added as the "token" query parameter.
+	URL string `yaml:"url"`
+}
+
+type EmailVerificationConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"24h"`
+	// URL is the page of the verification link, the token is added as the "token" query parameter.
+	URL string `yaml:"url"`
+	// ResendLimit is how often the verification email can be requested again per address within ResendWindow.
+	ResendLimit  int           `yaml:"resend_limit" env-default:"3"`
+	ResendWindow time.Duration `yaml:"resend_window" env-default:"1h"`
+}
+
+type EmailChangeConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// URL is the page of the confirmation link, the token is added as the "token" query parameter.
+	URL string `yaml:"url"`
+}
+
+type LockoutConfig struct {
+	// Window is how long failed logins are remembered after the last one.
+	Window time.Duration `yaml:"window" env-default:"15m"`
+	// AccountThreshold is the number of failed logins of an account before it is locked, 0 disables the check.
+	AccountThreshold int `yaml:"account_threshold" env-default:"5"`
+	// IPThreshold is the number of failed logins from a client IP before it is locked, 0 disables the check.
+	// The IP is the gRPC peer address, so disable it if all clients connect through the same proxy.
+	IPThreshold int `yaml:"ip_threshold" env-default:"20"`
+	// BaseDelay is the first lock duration, it doubles with every further failure up to MaxDelay.
+	BaseDelay time.Duration `yaml:"base_delay" env-default:"1m"`
+	MaxDelay  time.Duration `yaml:"max_delay" env-default:"1h"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" env-default:"6"`
+	// MaxLength is in bytes. Keep it at most 72 with bcrypt, which ignores everything past 72 bytes.
+	MaxLength     int  `yaml:"max_length" env-default:"72"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	// DisallowEmail rejects the email address or its local part as password.
+	DisallowEmail bool `yaml:"disallow_email" env-default:"true"`
+	// History is the number of previous passwords which can't be reused, 0 disables the check.
+	History int `yaml:"history" env-default:"5"`
+}
+
+type BreachedPasswordsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path is the SHA-1 "Pwned Passwords" file ordered by hash.
+	Path string `yaml:"path"`
+	// MinCount is how often a password has to appear in the file to be rejected.
+	MinCount int `yaml:"min_count" env-default:"1"`
+}
+
+type PasswordHashingConfig struct {
+	// Algorithm is either argon2id or bcrypt.
+	Algorithm  string `yaml:"algorithm" env-default:"argon2id"`
+	BcryptCost int    `yaml:"bcrypt_cost" env-default:"10"`
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32 `yaml:"argon2_memory" env-default:"65536"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env-default:"3"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env-default:"2"`
+	Argon2SaltLength  uint32 `yaml:"argon2_salt_length" env-default:"16"`
+	Argon2KeyLength   uint32 `yaml:"argon2_key_length" env-default:"32"`
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 
@@ -61,3 +182,35 @@ func fetchConfigPath() string {
 
 	return result
 }
+
+type PoliciesConfig struct {
+	// Source is either mongodb, which reads the policies collection, or file.
+	Source string `yaml:"source" env-default:"mongodb"`
+	// Path is the YAML policy file used with the file source.
+	Path string `yaml:"path"`
+	// Timezone is the IANA time zone of the time attributes of the policy environment.
+	Timezone string `yaml:"timezone" env-default:"UTC"`
+}
+
+type GroupsConfig struct {
+	// MaxDepth is how many levels of nested groups pass their permissions and roles on to members,
+	// 0 only takes the groups users are direct members of into account.
+	MaxDepth int `yaml:"max_depth" env-default:"5"`
+}
+
+type RelationsConfig struct {
+	// MaxDepth limits how many subject sets Check and Expand follow.
+	MaxDepth   int               `yaml:"max_depth" env-default:"10"`
+	Namespaces []NamespaceConfig `yaml:"namespaces"`
+}
+
+type NamespaceConfig struct {
+	Name      string           `yaml:"name"`
+	Relations []RelationConfig `yaml:"relations"`
+}
+
+type RelationConfig struct {
+	Name string `yaml:"name"`
+	// Includes are relations of the same object whose subjects also have this relation.
+	Includes []string `yaml:"includes"`
+}
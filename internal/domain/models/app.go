@@ -4,4 +4,40 @@ type App struct {
 	AppID  int
 	Name   string
 	Secret string
+	// SigningAlgorithm overrides the globally configured token signing algorithm for the app.
+	SigningAlgorithm string `bson:"signingAlgorithm,omitempty"`
+	// RequireVerifiedEmail rejects logins of users who did not verify their email address yet.
+	RequireVerifiedEmail bool `bson:"requireVerifiedEmail,omitempty"`
+	// EmailVerifiedClaim adds the email_verified claim to the app's tokens.
+	EmailVerifiedClaim bool `bson:"emailVerifiedClaim,omitempty"`
+	// AuthorizationClaims embeds the user's roles and permissions for the app in its tokens,
+	// either AuthorizationClaimsFull or AuthorizationClaimsHash. Empty embeds nothing.
+	AuthorizationClaims string `bson:"authorizationClaims,omitempty"`
+	// Claims customizes the claims of the app's tokens.
+	Claims ClaimTemplate `bson:"claims,omitempty"`
+	// Audience is the aud claim of the app's tokens, the services they are meant for.
+	// Empty uses the configured default audience.
+	Audience []string `bson:"audience,omitempty"`
+	// TokenTTLSeconds overrides the global access token TTL for the app, up to the configured maximum.
+	TokenTTLSeconds int `bson:"tokenTtlSeconds,omitempty"`
 }
+
+// ClaimTemplate selects the claims of an app's tokens on top of the ones the service always sets.
+// Claims the service sets itself, e.g. uid or exp, can't be overridden.
+type ClaimTemplate struct {
+	// Attributes adds user attributes as claims, keyed by claim name. {"org": "tenant"} adds the tenant
+	// attribute as org claim. Besides the user's attributes, email and email_verified can be selected.
+	// Users without the attribute get no claim.
+	Attributes map[string]string `bson:"attributes,omitempty"`
+	// Static claims are added to every token of the app. Values are strings, numbers, bools or lists of them.
+	Static map[string]interface{} `bson:"static,omitempty"`
+	// OmitEmail leaves out the email claim.
+	OmitEmail bool `bson:"omitEmail,omitempty"`
+}
+
+const (
+	// AuthorizationClaimsFull embeds the roles and permissions, or only their hash if they exceed the size limit.
+	AuthorizationClaimsFull = "full"
+	// AuthorizationClaimsHash embeds only the hash of the roles and permissions.
+	AuthorizationClaimsHash = "hash"
+)
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	AuditEventMFAEnabled               = "mfa.enabled"
+	AuditEventRecoveryCodeUsed         = "mfa.recovery_code.used"
+	AuditEventRecoveryCodesRegenerated = "mfa.recovery_codes.regenerated"
+	AuditEventPasswordReset            = "password.reset"
+	AuditEventPasswordChanged          = "password.changed"
+	AuditEventEmailChangeRequested     = "email.change_requested"
+	AuditEventEmailChanged             = "email.changed"
+	AuditEventEmailVerified            = "email.verified"
+	AuditEventAccountLocked            = "account.locked"
+	AuditEventAccountUnlocked          = "account.unlocked"
+	AuditEventRoleAssigned             = "role.assigned"
+	AuditEventRoleUnassigned           = "role.unassigned"
+	AuditEventGroupJoined              = "group.joined"
+	AuditEventGroupLeft                = "group.left"
+)
+
+// AuditEvent records a security relevant action on a user account.
+type AuditEvent struct {
+	EventId   string            `bson:"eventId"`
+	Type      string            `bson:"type"`
+	UserId    string            `bson:"userId"`
+	AppID     int               `bson:"appId,omitempty"`
+	Details   map[string]string `bson:"details,omitempty"`
+	CreatedAt time.Time         `bson:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Group grants its permissions and roles to its members. Members are users and other groups,
+// the members of a nested group are members of the enclosing groups as well.
+type Group struct {
+	Name string `bson:"name"`
+	// Groups are the groups this group is a member of.
+	Groups      []string         `bson:"groups,omitempty"`
+	Permissions []Permission     `bson:"permissions"`
+	Roles       []RoleAssignment `bson:"roles,omitempty"`
+	CreatedAt   time.Time        `bson:"createdAt"`
+}
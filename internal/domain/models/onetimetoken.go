@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	OneTimeTokenPurposePasswordReset     = "password_reset"
+	OneTimeTokenPurposeEmailVerification = "email_verification"
+	OneTimeTokenPurposeEmailChange       = "email_change"
+)
+
+// OneTimeToken is a single use token sent to the user, e.g. in a password reset link.
+// Only the hash of the token is stored.
+type OneTimeToken struct {
+	TokenId   string `bson:"tokenId"`
+	UserId    string `bson:"userId"`
+	Purpose   string `bson:"purpose"`
+	TokenHash string `bson:"tokenHash"`
+	// Email is the new email address of an email change.
+	Email     string     `bson:"email,omitempty"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	UsedAt    *time.Time `bson:"usedAt,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// Policy restricts a granted permission for an app, or for all apps if AppID is AppIDAll:
+// a deny policy rejects the permission whenever its condition holds, allow policies only let it
+// through when the condition of one of them holds. Policies never grant a permission. The condition is written in the language of lib/policy,
+// the permission may end in a wildcard segment like grants do.
+type Policy struct {
+	PolicyId    string `bson:"policyId"`
+	Description string `bson:"description,omitempty"`
+	AppID       int    `bson:"appId"`
+	Permission  string `bson:"permission"`
+	Effect      string `bson:"effect"`
+	Condition   string `bson:"condition"`
+}
+
+// AuthorizationAttributes are the attributes of the resource and the environment
+// an authorization check is made in, policy conditions can refer to them.
+type AuthorizationAttributes struct {
+	Resource    map[string]string
+	Environment map[string]string
+}
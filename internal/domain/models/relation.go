@@ -0,0 +1,33 @@
+package models
+
+// RelationTuple states that the subject has the relation to the object, e.g. "user 42 is viewer of document readme".
+type RelationTuple struct {
+	Namespace string          `bson:"namespace"`
+	ObjectId  string          `bson:"objectId"`
+	Relation  string          `bson:"relation"`
+	Subject   RelationSubject `bson:"subject"`
+}
+
+// RelationSubject is either a user or, if UserId is empty, the set of subjects which have
+// the relation to the object, e.g. "members of group admins".
+type RelationSubject struct {
+	UserId    string `bson:"userId,omitempty"`
+	Namespace string `bson:"namespace,omitempty"`
+	ObjectId  string `bson:"objectId,omitempty"`
+	Relation  string `bson:"relation,omitempty"`
+}
+
+// IsUser reports whether the subject is a single user rather than a subject set.
+func (s RelationSubject) IsUser() bool {
+	return s.UserId != ""
+}
+
+// UsersetTree is the expansion of the subjects having the relation to the object.
+// The subjects are the union of Users and the subjects of all Children.
+type UsersetTree struct {
+	Namespace string
+	ObjectId  string
+	Relation  string
+	Users     []string
+	Children  []UsersetTree
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Role bundles permissions which are granted to every user assigned to the role.
+type Role struct {
+	Name        string       `bson:"name"`
+	Permissions []Permission `bson:"permissions"`
+	CreatedAt   time.Time    `bson:"createdAt"`
+}
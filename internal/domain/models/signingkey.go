@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	// SigningKeyStatusActive keys sign new tokens.
+	SigningKeyStatusActive = "active"
+	// SigningKeyStatusRotated keys were replaced by a newer key, but still verify tokens until the overlap window ends.
+	SigningKeyStatusRotated = "rotated"
+	// SigningKeyStatusRetired keys are neither used for signing nor for verification.
+	SigningKeyStatusRetired = "retired"
+)
+
+type SigningKey struct {
+	KeyId string `bson:"keyId"`
+	// AppID is zero for keys shared by all apps.
+	AppID     int    `bson:"appId"`
+	Algorithm string `bson:"algorithm"`
+	Status    string `bson:"status"`
+	// PrivateKey is the PEM encoded private key, encrypted with the service encryption key.
+	PrivateKey []byte     `bson:"privateKey"`
+	CreatedAt  time.Time  `bson:"createdAt"`
+	RotatedAt  *time.Time `bson:"rotatedAt,omitempty"`
+	RetiredAt  *time.Time `bson:"retiredAt,omitempty"`
+}
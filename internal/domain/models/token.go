@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type LoginResult struct {
+	Tokens TokenPair
+	// MFAToken is returned instead of tokens when the user has to complete the login with a second factor.
+	MFAToken string
+}
+
+type RefreshToken struct {
+	TokenId   string     `bson:"tokenId"`
+	FamilyId  string     `bson:"familyId"`
+	UserId    string     `bson:"userId"`
+	AppID     int        `bson:"appId"`
+	TokenHash string     `bson:"tokenHash"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	UsedAt    *time.Time `bson:"usedAt,omitempty"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+}
+
+// Introspection describes a token as defined by RFC 7662.
+// Only Active is set for tokens which are invalid, expired, revoked or belong to a disabled user.
+type Introspection struct {
+	Active    bool
+	TokenId   string
+	UserId    string
+	Email     string
+	AppID     int
+	Scopes    []string
+	Issuer    string
+	Subject   string
+	Audience  []string
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
@@ -1,12 +1,74 @@
 package models
 
+import "time"
+
 type User struct {
-	UniqueId     string       `bson:"uniqueId"`
-	Email        string       `bson:"email"`
-	PasswordHash []byte       `bson:"passwordHash"`
-	Permissions  []Permission `bson:"permissions"`
+	UniqueId      string `bson:"uniqueId"`
+	Email         string `bson:"email"`
+	EmailVerified bool   `bson:"emailVerified"`
+	PasswordHash  []byte `bson:"passwordHash"`
+	// PasswordHistory holds the hashes of previous passwords, newest first.
+	PasswordHistory [][]byte         `bson:"passwordHistory,omitempty"`
+	Permissions     []Permission     `bson:"permissions"`
+	Roles           []RoleAssignment `bson:"roles,omitempty"`
+	// Groups are the groups the user is a direct member of.
+	Groups []string `bson:"groups,omitempty"`
+	// Attributes are free-form attributes of the user, e.g. the tenant, policies can refer to them.
+	Attributes map[string]string `bson:"attributes,omitempty"`
+	Disabled   bool              `bson:"disabled"`
+	MFA        MFA               `bson:"mfa"`
 }
 
+// AppIDAll scopes a permission or role assignment to all apps.
+const AppIDAll = 0
+
+// Permission is granted for a single app, or for all apps if AppID is AppIDAll.
+// The name may end in a wildcard segment, see lib/permission.
 type Permission struct {
-	Name string `bson:"name"`
+	Name  string `bson:"name"`
+	AppID int    `bson:"appId"`
+	// Deny rejects the permission even if another grant allows it.
+	Deny bool `bson:"deny,omitempty"`
+}
+
+// PermissionCheck asks whether the user holds the permission.
+type PermissionCheck struct {
+	UserId     string
+	Permission string
+}
+
+// UserAuthorization holds the roles and grants a user has for an app, including the ones it gets through groups.
+type UserAuthorization struct {
+	Roles       []string
+	Permissions []Permission
+}
+
+// RoleAssignment assigns a role to a user for a single app, or for all apps if AppID is AppIDAll.
+type RoleAssignment struct {
+	Role  string `bson:"role"`
+	AppID int    `bson:"appId"`
+}
+
+// MFA holds the TOTP second factor of a user. Secrets are encrypted with the service encryption key.
+type MFA struct {
+	Enabled bool   `bson:"enabled"`
+	Secret  []byte `bson:"secret,omitempty"`
+	// PendingSecret is the secret of an enrollment which has not been confirmed with a code yet.
+	PendingSecret []byte `bson:"pendingSecret,omitempty"`
+	// LastUsedStep is the TOTP time step of the last accepted code, codes can't be replayed within their window.
+	LastUsedStep int64 `bson:"lastUsedStep,omitempty"`
+	// RecoveryCodes are the keyed digests of the unused recovery codes.
+	RecoveryCodes [][]byte   `bson:"recoveryCodes,omitempty"`
+	EnrolledAt    *time.Time `bson:"enrolledAt,omitempty"`
+}
+
+type MFAEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAChallenge is a login which passed the password check and waits for the second factor.
+type MFAChallenge struct {
+	UserId string
+	AppID  int
 }
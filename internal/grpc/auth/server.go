@@ -1,15 +1,25 @@
 package authgrpc
 
 import (
+	"auth-sso/internal/domain/models"
 	"auth-sso/internal/services/auth"
+	"auth-sso/internal/services/keys"
+	"auth-sso/internal/services/relations"
+	"auth-sso/lib/grpcauth"
+	"auth-sso/lib/jwt"
+	"auth-sso/lib/passwordpolicy"
 	"auth-sso/lib/validation"
 	"context"
 	"errors"
 	authssov1 "github.com/alexprishmont/masters-protos/gen/go/auth-sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"log/slog"
+	"net"
 )
 
 type Auth interface {
@@ -17,7 +27,72 @@ type Auth interface {
 		email string,
 		password string,
 		appID int,
-	) (token string, err error)
+		clientIP string,
+	) (result models.LoginResult, err error)
+	EnrollMFA(ctx context.Context,
+		userId string,
+	) (enrollment models.MFAEnrollment, err error)
+	ConfirmMFA(ctx context.Context,
+		userId string,
+		code string,
+	) (recoveryCodes []string, err error)
+	RegenerateRecoveryCodes(ctx context.Context,
+		userId string,
+	) (recoveryCodes []string, err error)
+	VerifyMFA(ctx context.Context,
+		mfaToken string,
+		code string,
+		recoveryCode string,
+		clientIP string,
+	) (tokens models.TokenPair, err error)
+	Refresh(ctx context.Context,
+		refreshToken string,
+	) (tokens models.TokenPair, err error)
+	Logout(ctx context.Context,
+		token string,
+		refreshToken string,
+	) (err error)
+	Introspect(ctx context.Context,
+		token string,
+	) (introspection models.Introspection, err error)
+	Jwks(ctx context.Context) (keys []jwt.JWK, err error)
+	SigningKeys(ctx context.Context) (keys []models.SigningKey, err error)
+	RotateSigningKey(ctx context.Context,
+		appID int,
+	) (key models.SigningKey, err error)
+	RetireSigningKey(ctx context.Context,
+		keyId string,
+	) (err error)
+	RequestPasswordReset(ctx context.Context,
+		email string,
+	) (err error)
+	ResetPassword(ctx context.Context,
+		token string,
+		password string,
+	) (err error)
+	VerifyEmail(ctx context.Context,
+		token string,
+	) (err error)
+	ResendVerificationEmail(ctx context.Context,
+		email string,
+	) (err error)
+	ChangePassword(ctx context.Context,
+		userId string,
+		appID int,
+		currentPassword string,
+		newPassword string,
+	) (tokens models.TokenPair, err error)
+	ChangeEmail(ctx context.Context,
+		userId string,
+		password string,
+		newEmail string,
+	) (err error)
+	ConfirmEmailChange(ctx context.Context,
+		token string,
+	) (err error)
+	UnlockAccount(ctx context.Context,
+		email string,
+	) (err error)
 	RegisterNewUser(ctx context.Context,
 		email string,
 		password string,
@@ -25,13 +100,144 @@ type Auth interface {
 	Authorize(ctx context.Context,
 		permission string,
 		userId string,
+		appID int,
+		attributes models.AuthorizationAttributes,
 	) (isAuthorized bool, err error)
+	BatchAuthorize(ctx context.Context,
+		checks []models.PermissionCheck,
+		appID int,
+		attributes models.AuthorizationAttributes,
+	) (results []bool, err error)
+	CreateRole(ctx context.Context,
+		name string,
+		permissions []string,
+		appID int,
+	) (role models.Role, err error)
+	GrantRolePermission(ctx context.Context,
+		roleName string,
+		permission string,
+		appID int,
+		deny bool,
+	) (err error)
+	RevokeRolePermission(ctx context.Context,
+		roleName string,
+		permission string,
+		appID int,
+	) (err error)
+	AssignRole(ctx context.Context,
+		userId string,
+		roleName string,
+		appID int,
+	) (err error)
+	UnassignRole(ctx context.Context,
+		userId string,
+		roleName string,
+		appID int,
+	) (err error)
+	CreateGroup(ctx context.Context,
+		name string,
+	) (group models.Group, err error)
+	AddUserToGroup(ctx context.Context,
+		groupName string,
+		userId string,
+	) (err error)
+	RemoveUserFromGroup(ctx context.Context,
+		groupName string,
+		userId string,
+	) (err error)
+	AddSubgroup(ctx context.Context,
+		groupName string,
+		subgroupName string,
+	) (err error)
+	RemoveSubgroup(ctx context.Context,
+		groupName string,
+		subgroupName string,
+	) (err error)
+	GrantGroupPermission(ctx context.Context,
+		groupName string,
+		permission string,
+		appID int,
+		deny bool,
+	) (err error)
+	RevokeGroupPermission(ctx context.Context,
+		groupName string,
+		permission string,
+		appID int,
+	) (err error)
+	AssignGroupRole(ctx context.Context,
+		groupName string,
+		roleName string,
+		appID int,
+	) (err error)
+	UnassignGroupRole(ctx context.Context,
+		groupName string,
+		roleName string,
+		appID int,
+	) (err error)
+}
+
+type Relations interface {
+	Check(ctx context.Context,
+		namespace string,
+		objectId string,
+		relation string,
+		userId string,
+	) (allowed bool, err error)
+	Expand(ctx context.Context,
+		namespace string,
+		objectId string,
+		relation string,
+	) (tree models.UsersetTree, err error)
+	WriteTuples(ctx context.Context,
+		writes []models.RelationTuple,
+		deletes []models.RelationTuple,
+	) (err error)
+}
+
+// AuthenticatedMethods are the RPCs which require a bearer token.
+var AuthenticatedMethods = []string{
+	"Introspect",
+	"EnrollMFA",
+	"ConfirmMFA",
+	"RegenerateRecoveryCodes",
+	"ListSigningKeys",
+	"RotateSigningKey",
+	"RetireSigningKey",
+	"UnlockAccount",
+	"ChangePassword",
+	"ChangeEmail",
+	"CreateRole",
+	"GrantRolePermission",
+	"RevokeRolePermission",
+	"AssignRole",
+	"UnassignRole",
+	"CreateGroup",
+	"AddGroupMember",
+	"RemoveGroupMember",
+	"GrantGroupPermission",
+	"RevokeGroupPermission",
+	"AssignGroupRole",
+	"UnassignGroupRole",
+	"Check",
+	"Expand",
+	"WriteTuples",
 }
 
+const (
+	permissionIntrospect   = "auth-sso:tokens:introspect"
+	permissionManageKeys   = "auth-sso:keys:manage"
+	permissionManageUsers  = "auth-sso:users:manage"
+	permissionManageRoles  = "auth-sso:roles:manage"
+	permissionManageGroups = "auth-sso:groups:manage"
+	permissionReadTuples   = "auth-sso:relations:read"
+	permissionWriteTuples  = "auth-sso:relations:write"
+)
+
 type serverAPI struct {
 	authssov1.UnimplementedAuthServer
-	log  *slog.Logger
-	auth Auth
+	log       *slog.Logger
+	auth      Auth
+	relations Relations
 }
 
 type LoginRequest struct {
@@ -40,20 +246,181 @@ type LoginRequest struct {
 	AppID    int32  `validate:"required,number,gt=0"`
 }
 
+// RegisterRequest leaves the password rules to the password policy of the Auth service.
 type RegisterRequest struct {
 	Email    string `validate:"required,email"`
-	Password string `validate:"required,min=6"`
+	Password string `validate:"required"`
+}
+
+type RequestPasswordResetRequest struct {
+	Email string `validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `validate:"required"`
+	Password string `validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `validate:"required"`
+}
+
+type ResendVerificationEmailRequest struct {
+	Email string `validate:"required,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `validate:"required"`
+	NewPassword     string `validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `validate:"required,email"`
+	Password string `validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `validate:"required"`
+}
+
+type ConfirmMFARequest struct {
+	Code string `validate:"required,numeric,len=6"`
+}
+
+type VerifyMFARequest struct {
+	MfaToken     string `validate:"required"`
+	Code         string `validate:"omitempty,numeric,len=6"`
+	RecoveryCode string `validate:"required_without=Code,excluded_with=Code,max=32"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `validate:"required"`
+}
+
+type LogoutRequest struct {
+	Token        string `validate:"required"`
+	RefreshToken string
+}
+
+type IntrospectRequest struct {
+	Token string `validate:"required"`
+}
+
+type RotateSigningKeyRequest struct {
+	AppID int32 `validate:"number,gte=0"`
+}
+
+type RetireSigningKeyRequest struct {
+	KeyId string `validate:"required,uuid"`
+}
+
+type UnlockAccountRequest struct {
+	Email string `validate:"required,email"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `validate:"required,max=128"`
+	Permissions []string `validate:"dive,required"`
+	AppID       int32    `validate:"number,gte=0"`
+}
+
+type RolePermissionRequest struct {
+	Role       string `validate:"required"`
+	Permission string `validate:"required"`
+	AppID      int32  `validate:"number,gte=0"`
+	Deny       bool
+}
+
+type RoleAssignmentRequest struct {
+	UserId string `validate:"required"`
+	Role   string `validate:"required"`
+	AppID  int32  `validate:"number,gte=0"`
+}
+
+type CreateGroupRequest struct {
+	Name string `validate:"required,max=128"`
+}
+
+// GroupMemberRequest is about either a user or a subgroup of the group.
+type GroupMemberRequest struct {
+	Group    string `validate:"required"`
+	UserId   string `validate:"required_without=Subgroup,excluded_with=Subgroup"`
+	Subgroup string
 }
 
+type GroupPermissionRequest struct {
+	Group      string `validate:"required"`
+	Permission string `validate:"required"`
+	AppID      int32  `validate:"number,gte=0"`
+	Deny       bool
+}
+
+type GroupRoleRequest struct {
+	Group string `validate:"required"`
+	Role  string `validate:"required"`
+	AppID int32  `validate:"number,gte=0"`
+}
+
+// AuthorizeRequest checks the permission for the app. A zero AppID only accepts permissions granted for all apps.
 type AuthorizeRequest struct {
 	Permission string `validate:"required"`
 	UserId     string `validate:"required"`
+	AppID      int32  `validate:"number,gte=0"`
+}
+
+// maxBatchAuthorizeChecks caps the checks of a single BatchAuthorize call.
+const maxBatchAuthorizeChecks = 200
+
+type AuthorizationCheck struct {
+	UserId     string `validate:"required"`
+	Permission string `validate:"required"`
+}
+
+// BatchAuthorizeRequest holds the checks of the request, including the ones built from
+// the user_id and permissions shorthand.
+type BatchAuthorizeRequest struct {
+	Checks []AuthorizationCheck `validate:"required,min=1,dive"`
+	AppID  int32                `validate:"number,gte=0"`
+}
+
+type RelationCheckRequest struct {
+	Namespace string `validate:"required"`
+	ObjectId  string `validate:"required"`
+	Relation  string `validate:"required"`
+	UserId    string `validate:"required"`
+}
+
+type RelationExpandRequest struct {
+	Namespace string `validate:"required"`
+	ObjectId  string `validate:"required"`
+	Relation  string `validate:"required"`
 }
 
-func Register(gRPC *grpc.Server, log *slog.Logger, auth Auth) {
+// maxWriteTuples caps the writes and deletes of a single WriteTuples call.
+const maxWriteTuples = 100
+
+// RelationTuple is either about a user or about the subject set of another object relation,
+// the Relations service rejects tuples mixing both.
+type RelationTuple struct {
+	Namespace        string `validate:"required"`
+	ObjectId         string `validate:"required"`
+	Relation         string `validate:"required"`
+	SubjectUserId    string `validate:"required_without=SubjectObjectId"`
+	SubjectNamespace string `validate:"required_with=SubjectObjectId"`
+	SubjectObjectId  string
+	SubjectRelation  string `validate:"required_with=SubjectObjectId"`
+}
+
+type WriteTuplesRequest struct {
+	Writes  []RelationTuple `validate:"dive"`
+	Deletes []RelationTuple `validate:"dive"`
+}
+
+func Register(gRPC *grpc.Server, log *slog.Logger, auth Auth, relations Relations) {
 	authssov1.RegisterAuthServer(gRPC, &serverAPI{
-		log:  log,
-		auth: auth,
+		log:       log,
+		auth:      auth,
+		relations: relations,
 	})
 }
 
@@ -71,75 +438,1560 @@ func (s *serverAPI) Login(
 		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
 	}
 
-	token, err := s.auth.Login(ctx, req.Email, req.Password, int(req.AppID))
+	result, err := s.auth.Login(ctx, req.Email, req.Password, int(req.AppID), clientIP(ctx))
 
 	if err != nil {
 		if errors.Is(err, auth.ErrorInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
 		}
 
+		if errors.Is(err, auth.ErrorUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user is disabled")
+		}
+
+		if errors.Is(err, auth.ErrorEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email is not verified")
+		}
+
+		if errors.Is(err, auth.ErrorAccountLocked) {
+			return nil, status.Error(codes.ResourceExhausted, "too many failed login attempts, try again later")
+		}
+
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
+	if result.MFAToken != "" {
+		return &authssov1.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
+	}
+
 	return &authssov1.LoginResponse{
-		Token: token,
+		Token:        result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
 	}, nil
 }
 
-func (s *serverAPI) Register(
+func (s *serverAPI) EnrollMFA(
 	ctx context.Context,
-	request *authssov1.RegisterRequest,
-) (*authssov1.RegisterResponse, error) {
-	req := RegisterRequest{
-		Email:    request.GetEmail(),
-		Password: request.GetPassword(),
+	request *authssov1.EnrollMFARequest,
+) (*authssov1.EnrollMFAResponse, error) {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	enrollment, err := s.auth.EnrollMFA(ctx, principal.UserID)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorMFAAlreadyEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa is already enabled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.EnrollMFAResponse{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.URI,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmMFA(
+	ctx context.Context,
+	request *authssov1.ConfirmMFARequest,
+) (*authssov1.ConfirmMFAResponse, error) {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	req := ConfirmMFARequest{
+		Code: request.GetCode(),
 	}
 
 	if errStr := validation.ValidateStruct(req); errStr != "" {
 		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
 	}
 
-	userID, err := s.auth.RegisterNewUser(ctx, req.Email, req.Password)
+	recoveryCodes, err := s.auth.ConfirmMFA(ctx, principal.UserID, req.Code)
 
 	if err != nil {
-		if errors.Is(err, auth.ErrorUserExists) {
-			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		if errors.Is(err, auth.ErrorInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorMFAAlreadyEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa is already enabled")
+		}
+
+		if errors.Is(err, auth.ErrorMFANotPending) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa enrollment has not been started")
+		}
+
+		if errors.Is(err, auth.ErrorInvalidMFACode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid mfa code")
 		}
 
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &authssov1.RegisterResponse{
-		UserId: userID,
+	return &authssov1.ConfirmMFAResponse{
+		Success:       true,
+		RecoveryCodes: recoveryCodes,
 	}, nil
 }
 
-func (s *serverAPI) Authorize(
+func (s *serverAPI) RegenerateRecoveryCodes(
 	ctx context.Context,
-	request *authssov1.AuthorizeRequest,
-) (*authssov1.AuthorizeResponse, error) {
-	req := AuthorizeRequest{
-		Permission: request.GetPermission(),
-		UserId:     request.GetUserId(),
+	request *authssov1.RegenerateRecoveryCodesRequest,
+) (*authssov1.RegenerateRecoveryCodesResponse, error) {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	recoveryCodes, err := s.auth.RegenerateRecoveryCodes(ctx, principal.UserID)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorMFANotEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa is not enabled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RegenerateRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *serverAPI) VerifyMFA(
+	ctx context.Context,
+	request *authssov1.VerifyMFARequest,
+) (*authssov1.VerifyMFAResponse, error) {
+	req := VerifyMFARequest{
+		MfaToken:     request.GetMfaToken(),
+		Code:         request.GetCode(),
+		RecoveryCode: request.GetRecoveryCode(),
 	}
 
 	if errStr := validation.ValidateStruct(req); errStr != "" {
 		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
 	}
 
-	isAuthorized, err := s.auth.Authorize(ctx, req.Permission, req.UserId)
+	tokens, err := s.auth.VerifyMFA(ctx, req.MfaToken, req.Code, req.RecoveryCode, clientIP(ctx))
 
 	if err != nil {
-		if errors.Is(err, auth.ErrorUserNotAuthorized) {
-			return nil, status.Error(codes.Unauthenticated, "Unauthorized action")
+		if errors.Is(err, auth.ErrorInvalidMFAToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid mfa token")
+		}
+
+		if errors.Is(err, auth.ErrorInvalidMFACode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid mfa code")
+		}
+
+		if errors.Is(err, auth.ErrorUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user is disabled")
+		}
+
+		if errors.Is(err, auth.ErrorAccountLocked) {
+			return nil, status.Error(codes.ResourceExhausted, "too many failed login attempts, try again later")
 		}
 
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &authssov1.AuthorizeResponse{
-		Can:        isAuthorized,
-		UserId:     req.UserId,
-		Permission: req.Permission,
+	return &authssov1.VerifyMFAResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	request *authssov1.RefreshRequest,
+) (*authssov1.RefreshResponse, error) {
+	req := RefreshRequest{
+		RefreshToken: request.GetRefreshToken(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	tokens, err := s.auth.Refresh(ctx, req.RefreshToken)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	request *authssov1.LogoutRequest,
+) (*authssov1.LogoutResponse, error) {
+	req := LogoutRequest{
+		Token:        request.GetToken(),
+		RefreshToken: request.GetRefreshToken(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.Logout(ctx, req.Token, req.RefreshToken)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if errors.Is(err, auth.ErrorInvalidRefreshToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid refresh token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.LogoutResponse{
+		Success: true,
 	}, nil
 }
+
+func (s *serverAPI) Introspect(
+	ctx context.Context,
+	request *authssov1.IntrospectRequest,
+) (*authssov1.IntrospectResponse, error) {
+	if err := s.requirePermission(ctx, permissionIntrospect); err != nil {
+		return nil, err
+	}
+
+	req := IntrospectRequest{
+		Token: request.GetToken(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	introspection, err := s.auth.Introspect(ctx, req.Token)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if !introspection.Active {
+		return &authssov1.IntrospectResponse{
+			Active: false,
+		}, nil
+	}
+
+	response := &authssov1.IntrospectResponse{
+		Active: true,
+		Jti:    introspection.TokenId,
+		UserId: introspection.UserId,
+		Email:  introspection.Email,
+		AppId:  int32(introspection.AppID),
+		Scopes: introspection.Scopes,
+		Iss:    introspection.Issuer,
+		Sub:    introspection.Subject,
+		Aud:    introspection.Audience,
+		Exp:    introspection.ExpiresAt.Unix(),
+	}
+
+	// Tokens issued before the iat and nbf claims were introduced have none.
+	if !introspection.IssuedAt.IsZero() {
+		response.Iat = introspection.IssuedAt.Unix()
+	}
+
+	if !introspection.NotBefore.IsZero() {
+		response.Nbf = introspection.NotBefore.Unix()
+	}
+
+	return response, nil
+}
+
+func (s *serverAPI) Jwks(
+	ctx context.Context,
+	request *authssov1.JwksRequest,
+) (*authssov1.JwksResponse, error) {
+	keys, err := s.auth.Jwks(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	response := &authssov1.JwksResponse{
+		Keys: make([]*authssov1.Jwk, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		response.Keys = append(response.Keys, &authssov1.Jwk{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *serverAPI) ListSigningKeys(
+	ctx context.Context,
+	request *authssov1.ListSigningKeysRequest,
+) (*authssov1.ListSigningKeysResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageKeys); err != nil {
+		return nil, err
+	}
+
+	signingKeys, err := s.auth.SigningKeys(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	response := &authssov1.ListSigningKeysResponse{
+		Keys: make([]*authssov1.SigningKey, 0, len(signingKeys)),
+	}
+
+	for _, key := range signingKeys {
+		response.Keys = append(response.Keys, signingKeyToProto(key))
+	}
+
+	return response, nil
+}
+
+func (s *serverAPI) RotateSigningKey(
+	ctx context.Context,
+	request *authssov1.RotateSigningKeyRequest,
+) (*authssov1.RotateSigningKeyResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageKeys); err != nil {
+		return nil, err
+	}
+
+	req := RotateSigningKeyRequest{
+		AppID: request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	key, err := s.auth.RotateSigningKey(ctx, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		if errors.Is(err, keys.ErrorSymmetricKey) {
+			return nil, status.Error(codes.FailedPrecondition, "app signs tokens with its secret")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RotateSigningKeyResponse{
+		Key: signingKeyToProto(key),
+	}, nil
+}
+
+func (s *serverAPI) RetireSigningKey(
+	ctx context.Context,
+	request *authssov1.RetireSigningKeyRequest,
+) (*authssov1.RetireSigningKeyResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageKeys); err != nil {
+		return nil, err
+	}
+
+	req := RetireSigningKeyRequest{
+		KeyId: request.GetKeyId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.RetireSigningKey(ctx, req.KeyId)
+
+	if err != nil {
+		if errors.Is(err, keys.ErrorKeyNotFound) {
+			return nil, status.Error(codes.NotFound, "signing key not found")
+		}
+
+		if errors.Is(err, keys.ErrorActiveKeyRetiring) {
+			return nil, status.Error(codes.FailedPrecondition, "active signing key can not be retired")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RetireSigningKeyResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) RequestPasswordReset(
+	ctx context.Context,
+	request *authssov1.RequestPasswordResetRequest,
+) (*authssov1.RequestPasswordResetResponse, error) {
+	req := RequestPasswordResetRequest{
+		Email: request.GetEmail(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.RequestPasswordReset(ctx, req.Email)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RequestPasswordResetResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) ResetPassword(
+	ctx context.Context,
+	request *authssov1.ResetPasswordRequest,
+) (*authssov1.ResetPasswordResponse, error) {
+	req := ResetPasswordRequest{
+		Token:    request.GetToken(),
+		Password: request.GetPassword(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.ResetPassword(ctx, req.Token, req.Password)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidResetToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired reset token")
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr)
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.ResetPasswordResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) VerifyEmail(
+	ctx context.Context,
+	request *authssov1.VerifyEmailRequest,
+) (*authssov1.VerifyEmailResponse, error) {
+	req := VerifyEmailRequest{
+		Token: request.GetToken(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.VerifyEmail(ctx, req.Token)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidVerifyToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired verification token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.VerifyEmailResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) ResendVerificationEmail(
+	ctx context.Context,
+	request *authssov1.ResendVerificationEmailRequest,
+) (*authssov1.ResendVerificationEmailResponse, error) {
+	req := ResendVerificationEmailRequest{
+		Email: request.GetEmail(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.ResendVerificationEmail(ctx, req.Email)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorTooManyRequests) {
+			return nil, status.Error(codes.ResourceExhausted, "too many requests, try again later")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.ResendVerificationEmailResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) ChangePassword(
+	ctx context.Context,
+	request *authssov1.ChangePasswordRequest,
+) (*authssov1.ChangePasswordResponse, error) {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	req := ChangePasswordRequest{
+		CurrentPassword: request.GetCurrentPassword(),
+		NewPassword:     request.GetNewPassword(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	tokens, err := s.auth.ChangePassword(ctx, principal.UserID, principal.AppID, req.CurrentPassword, req.NewPassword)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid current password")
+		}
+
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr)
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.ChangePasswordResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) ChangeEmail(
+	ctx context.Context,
+	request *authssov1.ChangeEmailRequest,
+) (*authssov1.ChangeEmailResponse, error) {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	req := ChangeEmailRequest{
+		NewEmail: request.GetNewEmail(),
+		Password: request.GetPassword(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.ChangeEmail(ctx, principal.UserID, req.Password, req.NewEmail)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid password")
+		}
+
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorUserExists) {
+			return nil, status.Error(codes.AlreadyExists, "email address is already in use")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.ChangeEmailResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmEmailChange(
+	ctx context.Context,
+	request *authssov1.ConfirmEmailChangeRequest,
+) (*authssov1.ConfirmEmailChangeResponse, error) {
+	req := ConfirmEmailChangeRequest{
+		Token: request.GetToken(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.ConfirmEmailChange(ctx, req.Token)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidChangeToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired email change token")
+		}
+
+		if errors.Is(err, auth.ErrorUserExists) {
+			return nil, status.Error(codes.AlreadyExists, "email address is already in use")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.ConfirmEmailChangeResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) UnlockAccount(
+	ctx context.Context,
+	request *authssov1.UnlockAccountRequest,
+) (*authssov1.UnlockAccountResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageUsers); err != nil {
+		return nil, err
+	}
+
+	req := UnlockAccountRequest{
+		Email: request.GetEmail(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.UnlockAccount(ctx, req.Email)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.UnlockAccountResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) Register(
+	ctx context.Context,
+	request *authssov1.RegisterRequest,
+) (*authssov1.RegisterResponse, error) {
+	req := RegisterRequest{
+		Email:    request.GetEmail(),
+		Password: request.GetPassword(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	userID, err := s.auth.RegisterNewUser(ctx, req.Email, req.Password)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserExists) {
+			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr)
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RegisterResponse{
+		UserId: userID,
+	}, nil
+}
+
+func (s *serverAPI) Authorize(
+	ctx context.Context,
+	request *authssov1.AuthorizeRequest,
+) (*authssov1.AuthorizeResponse, error) {
+	req := AuthorizeRequest{
+		Permission: request.GetPermission(),
+		UserId:     request.GetUserId(),
+		AppID:      request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	attributes := models.AuthorizationAttributes{
+		Resource:    request.GetResource(),
+		Environment: request.GetEnvironment(),
+	}
+
+	isAuthorized, err := s.auth.Authorize(ctx, req.Permission, req.UserId, int(req.AppID), attributes)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotAuthorized) {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized action")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.AuthorizeResponse{
+		Can:        isAuthorized,
+		UserId:     req.UserId,
+		Permission: req.Permission,
+		AppId:      req.AppID,
+	}, nil
+}
+
+func (s *serverAPI) BatchAuthorize(
+	ctx context.Context,
+	request *authssov1.BatchAuthorizeRequest,
+) (*authssov1.BatchAuthorizeResponse, error) {
+	if len(request.GetPermissions())+len(request.GetChecks()) > maxBatchAuthorizeChecks {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d checks are allowed", maxBatchAuthorizeChecks)
+	}
+
+	req := BatchAuthorizeRequest{
+		Checks: make([]AuthorizationCheck, 0, len(request.GetPermissions())+len(request.GetChecks())),
+		AppID:  request.GetAppId(),
+	}
+
+	for _, permission := range request.GetPermissions() {
+		req.Checks = append(req.Checks, AuthorizationCheck{
+			UserId:     request.GetUserId(),
+			Permission: permission,
+		})
+	}
+
+	for _, check := range request.GetChecks() {
+		req.Checks = append(req.Checks, AuthorizationCheck{
+			UserId:     check.GetUserId(),
+			Permission: check.GetPermission(),
+		})
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	checks := make([]models.PermissionCheck, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		checks = append(checks, models.PermissionCheck{
+			UserId:     check.UserId,
+			Permission: check.Permission,
+		})
+	}
+
+	attributes := models.AuthorizationAttributes{
+		Resource:    request.GetResource(),
+		Environment: request.GetEnvironment(),
+	}
+
+	results, err := s.auth.BatchAuthorize(ctx, checks, int(req.AppID), attributes)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotAuthorized) {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized action")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	response := &authssov1.BatchAuthorizeResponse{
+		Results: make([]*authssov1.AuthorizationResult, 0, len(results)),
+		AppId:   req.AppID,
+	}
+
+	for i, can := range results {
+		response.Results = append(response.Results, &authssov1.AuthorizationResult{
+			UserId:     checks[i].UserId,
+			Permission: checks[i].Permission,
+			Can:        can,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *serverAPI) CreateRole(
+	ctx context.Context,
+	request *authssov1.CreateRoleRequest,
+) (*authssov1.CreateRoleResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	req := CreateRoleRequest{
+		Name:        request.GetName(),
+		Permissions: request.GetPermissions(),
+		AppID:       request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	role, err := s.auth.CreateRole(ctx, req.Name, req.Permissions, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorRoleExists) {
+			return nil, status.Error(codes.AlreadyExists, "role already exists")
+		}
+
+		if errors.Is(err, auth.ErrorInvalidPermission) {
+			return nil, status.Error(codes.InvalidArgument, "invalid permission name")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.CreateRoleResponse{
+		Role: roleToProto(role),
+	}, nil
+}
+
+func (s *serverAPI) GrantRolePermission(
+	ctx context.Context,
+	request *authssov1.GrantRolePermissionRequest,
+) (*authssov1.GrantRolePermissionResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	req := RolePermissionRequest{
+		Role:       request.GetRole(),
+		Permission: request.GetPermission(),
+		AppID:      request.GetAppId(),
+		Deny:       request.GetDeny(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.GrantRolePermission(ctx, req.Role, req.Permission, int(req.AppID), req.Deny)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidPermission) {
+			return nil, status.Error(codes.InvalidArgument, "invalid permission name")
+		}
+
+		if errors.Is(err, auth.ErrorRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "role not found")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.GrantRolePermissionResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) RevokeRolePermission(
+	ctx context.Context,
+	request *authssov1.RevokeRolePermissionRequest,
+) (*authssov1.RevokeRolePermissionResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	req := RolePermissionRequest{
+		Role:       request.GetRole(),
+		Permission: request.GetPermission(),
+		AppID:      request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.RevokeRolePermission(ctx, req.Role, req.Permission, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "role not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RevokeRolePermissionResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) AssignRole(
+	ctx context.Context,
+	request *authssov1.AssignRoleRequest,
+) (*authssov1.AssignRoleResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	req := RoleAssignmentRequest{
+		UserId: request.GetUserId(),
+		Role:   request.GetRole(),
+		AppID:  request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.AssignRole(ctx, req.UserId, req.Role, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "role not found")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.AssignRoleResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) UnassignRole(
+	ctx context.Context,
+	request *authssov1.UnassignRoleRequest,
+) (*authssov1.UnassignRoleResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	req := RoleAssignmentRequest{
+		UserId: request.GetUserId(),
+		Role:   request.GetRole(),
+		AppID:  request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.UnassignRole(ctx, req.UserId, req.Role, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.UnassignRoleResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) CreateGroup(
+	ctx context.Context,
+	request *authssov1.CreateGroupRequest,
+) (*authssov1.CreateGroupResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := CreateGroupRequest{
+		Name: request.GetName(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	group, err := s.auth.CreateGroup(ctx, req.Name)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorGroupExists) {
+			return nil, status.Error(codes.AlreadyExists, "group already exists")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.CreateGroupResponse{
+		Group: groupToProto(group),
+	}, nil
+}
+
+func (s *serverAPI) AddGroupMember(
+	ctx context.Context,
+	request *authssov1.AddGroupMemberRequest,
+) (*authssov1.AddGroupMemberResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupMemberRequest{
+		Group:    request.GetGroup(),
+		UserId:   request.GetUserId(),
+		Subgroup: request.GetSubgroup(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	var err error
+	if req.UserId != "" {
+		err = s.auth.AddUserToGroup(ctx, req.Group, req.UserId)
+	} else {
+		err = s.auth.AddSubgroup(ctx, req.Group, req.Subgroup)
+	}
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		if errors.Is(err, auth.ErrorGroupCycle) {
+			return nil, status.Error(codes.FailedPrecondition, "group nesting would form a cycle")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.AddGroupMemberResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) RemoveGroupMember(
+	ctx context.Context,
+	request *authssov1.RemoveGroupMemberRequest,
+) (*authssov1.RemoveGroupMemberResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupMemberRequest{
+		Group:    request.GetGroup(),
+		UserId:   request.GetUserId(),
+		Subgroup: request.GetSubgroup(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	var err error
+	if req.UserId != "" {
+		err = s.auth.RemoveUserFromGroup(ctx, req.Group, req.UserId)
+	} else {
+		err = s.auth.RemoveSubgroup(ctx, req.Group, req.Subgroup)
+	}
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RemoveGroupMemberResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) GrantGroupPermission(
+	ctx context.Context,
+	request *authssov1.GrantGroupPermissionRequest,
+) (*authssov1.GrantGroupPermissionResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupPermissionRequest{
+		Group:      request.GetGroup(),
+		Permission: request.GetPermission(),
+		AppID:      request.GetAppId(),
+		Deny:       request.GetDeny(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.GrantGroupPermission(ctx, req.Group, req.Permission, int(req.AppID), req.Deny)
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorInvalidPermission) {
+			return nil, status.Error(codes.InvalidArgument, "invalid permission name")
+		}
+
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.GrantGroupPermissionResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) RevokeGroupPermission(
+	ctx context.Context,
+	request *authssov1.RevokeGroupPermissionRequest,
+) (*authssov1.RevokeGroupPermissionResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupPermissionRequest{
+		Group:      request.GetGroup(),
+		Permission: request.GetPermission(),
+		AppID:      request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.RevokeGroupPermission(ctx, req.Group, req.Permission, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.RevokeGroupPermissionResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) AssignGroupRole(
+	ctx context.Context,
+	request *authssov1.AssignGroupRoleRequest,
+) (*authssov1.AssignGroupRoleResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupRoleRequest{
+		Group: request.GetGroup(),
+		Role:  request.GetRole(),
+		AppID: request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.AssignGroupRole(ctx, req.Group, req.Role, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		if errors.Is(err, auth.ErrorRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "role not found")
+		}
+
+		if errors.Is(err, auth.ErrorAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.AssignGroupRoleResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) UnassignGroupRole(
+	ctx context.Context,
+	request *authssov1.UnassignGroupRoleRequest,
+) (*authssov1.UnassignGroupRoleResponse, error) {
+	if err := s.requirePermission(ctx, permissionManageGroups); err != nil {
+		return nil, err
+	}
+
+	req := GroupRoleRequest{
+		Group: request.GetGroup(),
+		Role:  request.GetRole(),
+		AppID: request.GetAppId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.auth.UnassignGroupRole(ctx, req.Group, req.Role, int(req.AppID))
+
+	if err != nil {
+		if errors.Is(err, auth.ErrorGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authssov1.UnassignGroupRoleResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) Check(
+	ctx context.Context,
+	request *authssov1.CheckRequest,
+) (*authssov1.CheckResponse, error) {
+	if err := s.requirePermission(ctx, permissionReadTuples); err != nil {
+		return nil, err
+	}
+
+	req := RelationCheckRequest{
+		Namespace: request.GetNamespace(),
+		ObjectId:  request.GetObjectId(),
+		Relation:  request.GetRelation(),
+		UserId:    request.GetUserId(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	allowed, err := s.relations.Check(ctx, req.Namespace, req.ObjectId, req.Relation, req.UserId)
+
+	if err != nil {
+		return nil, relationsStatus(err)
+	}
+
+	return &authssov1.CheckResponse{
+		Allowed: allowed,
+	}, nil
+}
+
+func (s *serverAPI) Expand(
+	ctx context.Context,
+	request *authssov1.ExpandRequest,
+) (*authssov1.ExpandResponse, error) {
+	if err := s.requirePermission(ctx, permissionReadTuples); err != nil {
+		return nil, err
+	}
+
+	req := RelationExpandRequest{
+		Namespace: request.GetNamespace(),
+		ObjectId:  request.GetObjectId(),
+		Relation:  request.GetRelation(),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	tree, err := s.relations.Expand(ctx, req.Namespace, req.ObjectId, req.Relation)
+
+	if err != nil {
+		return nil, relationsStatus(err)
+	}
+
+	return &authssov1.ExpandResponse{
+		Tree: usersetTreeToProto(tree),
+	}, nil
+}
+
+func (s *serverAPI) WriteTuples(
+	ctx context.Context,
+	request *authssov1.WriteTuplesRequest,
+) (*authssov1.WriteTuplesResponse, error) {
+	if err := s.requirePermission(ctx, permissionWriteTuples); err != nil {
+		return nil, err
+	}
+
+	if len(request.GetWrites())+len(request.GetDeletes()) > maxWriteTuples {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d tuples are allowed", maxWriteTuples)
+	}
+
+	req := WriteTuplesRequest{
+		Writes:  relationTuplesFromProto(request.GetWrites()),
+		Deletes: relationTuplesFromProto(request.GetDeletes()),
+	}
+
+	if errStr := validation.ValidateStruct(req); errStr != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", errStr)
+	}
+
+	err := s.relations.WriteTuples(ctx, relationTuplesToModel(req.Writes), relationTuplesToModel(req.Deletes))
+
+	if err != nil {
+		return nil, relationsStatus(err)
+	}
+
+	return &authssov1.WriteTuplesResponse{
+		Success: true,
+	}, nil
+}
+
+func signingKeyToProto(key models.SigningKey) *authssov1.SigningKey {
+	result := &authssov1.SigningKey{
+		KeyId:     key.KeyId,
+		AppId:     int32(key.AppID),
+		Algorithm: key.Algorithm,
+		Status:    key.Status,
+		CreatedAt: timestamppb.New(key.CreatedAt),
+	}
+
+	if key.RotatedAt != nil {
+		result.RotatedAt = timestamppb.New(*key.RotatedAt)
+	}
+
+	if key.RetiredAt != nil {
+		result.RetiredAt = timestamppb.New(*key.RetiredAt)
+	}
+
+	return result
+}
+
+func roleToProto(role models.Role) *authssov1.Role {
+	result := &authssov1.Role{
+		Name:        role.Name,
+		Permissions: make([]*authssov1.Permission, 0, len(role.Permissions)),
+		CreatedAt:   timestamppb.New(role.CreatedAt),
+	}
+
+	for _, permission := range role.Permissions {
+		result.Permissions = append(result.Permissions, &authssov1.Permission{
+			Name:  permission.Name,
+			AppId: int32(permission.AppID),
+			Deny:  permission.Deny,
+		})
+	}
+
+	return result
+}
+
+func groupToProto(group models.Group) *authssov1.Group {
+	result := &authssov1.Group{
+		Name:        group.Name,
+		Groups:      group.Groups,
+		Permissions: make([]*authssov1.Permission, 0, len(group.Permissions)),
+		Roles:       make([]*authssov1.RoleAssignment, 0, len(group.Roles)),
+		CreatedAt:   timestamppb.New(group.CreatedAt),
+	}
+
+	for _, permission := range group.Permissions {
+		result.Permissions = append(result.Permissions, &authssov1.Permission{
+			Name:  permission.Name,
+			AppId: int32(permission.AppID),
+			Deny:  permission.Deny,
+		})
+	}
+
+	for _, assignment := range group.Roles {
+		result.Roles = append(result.Roles, &authssov1.RoleAssignment{
+			Role:  assignment.Role,
+			AppId: int32(assignment.AppID),
+		})
+	}
+
+	return result
+}
+
+func usersetTreeToProto(tree models.UsersetTree) *authssov1.UsersetTree {
+	result := &authssov1.UsersetTree{
+		Namespace: tree.Namespace,
+		ObjectId:  tree.ObjectId,
+		Relation:  tree.Relation,
+		Users:     tree.Users,
+		Children:  make([]*authssov1.UsersetTree, 0, len(tree.Children)),
+	}
+
+	for _, child := range tree.Children {
+		result.Children = append(result.Children, usersetTreeToProto(child))
+	}
+
+	return result
+}
+
+func relationTuplesFromProto(tuples []*authssov1.RelationTuple) []RelationTuple {
+	result := make([]RelationTuple, 0, len(tuples))
+
+	for _, tuple := range tuples {
+		subject := tuple.GetSubject()
+
+		result = append(result, RelationTuple{
+			Namespace:        tuple.GetNamespace(),
+			ObjectId:         tuple.GetObjectId(),
+			Relation:         tuple.GetRelation(),
+			SubjectUserId:    subject.GetUserId(),
+			SubjectNamespace: subject.GetNamespace(),
+			SubjectObjectId:  subject.GetObjectId(),
+			SubjectRelation:  subject.GetRelation(),
+		})
+	}
+
+	return result
+}
+
+func relationTuplesToModel(tuples []RelationTuple) []models.RelationTuple {
+	result := make([]models.RelationTuple, 0, len(tuples))
+
+	for _, tuple := range tuples {
+		result = append(result, models.RelationTuple{
+			Namespace: tuple.Namespace,
+			ObjectId:  tuple.ObjectId,
+			Relation:  tuple.Relation,
+			Subject: models.RelationSubject{
+				UserId:    tuple.SubjectUserId,
+				Namespace: tuple.SubjectNamespace,
+				ObjectId:  tuple.SubjectObjectId,
+				Relation:  tuple.SubjectRelation,
+			},
+		})
+	}
+
+	return result
+}
+
+// relationsStatus maps errors of the Relations service to gRPC statuses.
+func relationsStatus(err error) error {
+	switch {
+	case errors.Is(err, relations.ErrorUnknownNamespace):
+		return status.Error(codes.InvalidArgument, "unknown namespace")
+	case errors.Is(err, relations.ErrorUnknownRelation):
+		return status.Error(codes.InvalidArgument, "unknown relation")
+	case errors.Is(err, relations.ErrorInvalidTuple):
+		return status.Error(codes.InvalidArgument, "invalid relation tuple")
+	case errors.Is(err, relations.ErrorDepthExceeded):
+		return status.Error(codes.FailedPrecondition, "maximum relation depth exceeded")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// requirePermission checks that the authenticated caller holds the permission.
+func (s *serverAPI) requirePermission(ctx context.Context, permission string) error {
+	principal, ok := grpcauth.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	can, err := s.auth.Authorize(ctx, permission, principal.UserID, principal.AppID, models.AuthorizationAttributes{})
+	if err != nil {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	if !can {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return nil
+}
+
+// passwordPolicyStatus returns an InvalidArgument status carrying the policy violations as BadRequest details.
+func passwordPolicyStatus(policyErr *passwordpolicy.Error) error {
+	st := status.New(codes.InvalidArgument, "password does not satisfy the password policy")
+
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       "password",
+			Description: violation.Description,
+		})
+	}
+
+	withDetails, err := st.WithDetails(badRequest)
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// clientIP returns the IP address of the peer calling the RPC.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
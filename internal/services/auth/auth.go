@@ -3,22 +3,90 @@ package auth
 import (
 	"auth-sso/internal/domain/models"
 	"auth-sso/internal/storage"
+	"auth-sso/lib/hasher"
 	"auth-sso/lib/jwt"
+	"auth-sso/lib/passwordpolicy"
 	"context"
 	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 type Auth struct {
-	log                *slog.Logger
-	userSaver          UserSaver
-	userProvider       UserProvider
-	appProvider        AppProvider
-	permissionProvider PermissionProvider
-	tokenTTL           time.Duration
+	log                  *slog.Logger
+	asynqClient          *asynq.Client
+	userSaver            UserSaver
+	userProvider         UserProvider
+	appProvider          AppProvider
+	permissionProvider   PermissionProvider
+	roleSaver            RoleSaver
+	roleProvider         RoleProvider
+	groupSaver           GroupSaver
+	groupProvider        GroupProvider
+	policyProvider       PolicyProvider
+	refreshTokenSaver    RefreshTokenSaver
+	refreshTokenProvider RefreshTokenProvider
+	tokenRevoker         TokenRevoker
+	revokedTokenProvider RevokedTokenProvider
+	keyProvider          KeyProvider
+	mfaSaver             MFASaver
+	mfaChallengeSaver    MFAChallengeSaver
+	mfaChallengeProvider MFAChallengeProvider
+	auditEventSaver      AuditEventSaver
+	oneTimeTokenSaver    OneTimeTokenSaver
+	oneTimeTokenProvider OneTimeTokenProvider
+	loginAttemptTracker  LoginAttemptTracker
+	requestLimiter       RequestLimiter
+	breachChecker        BreachChecker
+	cfg                  Config
+	// conditions caches the compiled policy conditions by their source.
+	conditions sync.Map
+}
+
+// Config holds the settings of the Auth service.
+type Config struct {
+	TokenTTL        time.Duration
+	MaxTokenTTL     time.Duration
+	RefreshTokenTTL time.Duration
+	// EncryptionKey encrypts the TOTP secrets stored with the user and keys the recovery code digests.
+	EncryptionKey   []byte
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+	MFAMaxAttempts  int
+	// RecoveryCodeCount is the number of recovery codes handed out on MFA enrollment.
+	RecoveryCodeCount int
+	PasswordResetTTL  time.Duration
+	// PasswordResetURL is the page the reset link points to, the token is added as a query parameter.
+	PasswordResetURL     string
+	EmailVerificationTTL time.Duration
+	// EmailVerificationURL is the page the verification link points to, the token is added as a query parameter.
+	EmailVerificationURL string
+	EmailChangeTTL       time.Duration
+	// EmailChangeURL is the page the email change confirmation link points to, the token is added as a query parameter.
+	EmailChangeURL string
+	Lockout        LockoutConfig
+	PasswordPolicy passwordpolicy.Policy
+	// PasswordHasher hashes new passwords. Existing hashes of other algorithms or parameters
+	// are replaced on the next successful login.
+	PasswordHasher hasher.Hasher
+	// BreachMinCount is how often a password has to appear in the breach corpus to be rejected.
+	BreachMinCount int
+	// PolicyLocation is the time zone of the time attributes of the policy environment.
+	PolicyLocation *time.Location
+	// Issuer is the iss claim of issued tokens, tokens of other issuers are rejected.
+	Issuer string
+	// Audience is the aud claim of tokens of apps without their own audience.
+	Audience []string
+	// AuthorizationClaimsMaxSize is the maximum size in bytes of the roles and permissions embedded in tokens.
+	AuthorizationClaimsMaxSize int
+	// VerificationResendLimit is how often the verification email can be requested per address
+	// within VerificationResendWindow.
+	VerificationResendLimit  int
+	VerificationResendWindow time.Duration
 }
 
 type UserSaver interface {
@@ -26,10 +94,15 @@ type UserSaver interface {
 		email string,
 		passHash []byte,
 	) (uid string, err error)
+	UpdatePassword(ctx context.Context, userId string, passHash []byte, history [][]byte) error
+	RehashPassword(ctx context.Context, userId string, oldHash []byte, newHash []byte) error
+	UpdateEmail(ctx context.Context, userId string, email string) error
+	MarkEmailVerified(ctx context.Context, userId string) error
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (models.User, error)
+	UserById(ctx context.Context, id string) (models.User, error)
 }
 
 type AppProvider interface {
@@ -37,45 +110,238 @@ type AppProvider interface {
 }
 
 type PermissionProvider interface {
-	Can(ctx context.Context, permission string, userId string) (bool, error)
+	Can(ctx context.Context, permission string, userId string, appID int) (bool, error)
+	CanBatch(ctx context.Context, checks []models.PermissionCheck, appID int) ([]bool, error)
+	UserAuthorization(ctx context.Context, userId string, appID int) (models.UserAuthorization, error)
+}
+
+type RoleSaver interface {
+	SaveRole(ctx context.Context, role models.Role) error
+	GrantRolePermission(ctx context.Context, roleName string, permission models.Permission) error
+	RevokeRolePermission(ctx context.Context, roleName string, permission models.Permission) error
+	AssignRole(ctx context.Context, userId string, assignment models.RoleAssignment) error
+	UnassignRole(ctx context.Context, userId string, assignment models.RoleAssignment) error
+}
+
+type RoleProvider interface {
+	Role(ctx context.Context, name string) (models.Role, error)
+}
+
+type GroupSaver interface {
+	SaveGroup(ctx context.Context, group models.Group) error
+	AddUserToGroup(ctx context.Context, userId string, groupName string) error
+	RemoveUserFromGroup(ctx context.Context, userId string, groupName string) error
+	AddSubgroup(ctx context.Context, groupName string, subgroupName string) error
+	RemoveSubgroup(ctx context.Context, groupName string, subgroupName string) error
+	GrantGroupPermission(ctx context.Context, groupName string, permission models.Permission) error
+	RevokeGroupPermission(ctx context.Context, groupName string, permission models.Permission) error
+	AssignGroupRole(ctx context.Context, groupName string, assignment models.RoleAssignment) error
+	UnassignGroupRole(ctx context.Context, groupName string, assignment models.RoleAssignment) error
+}
+
+type GroupProvider interface {
+	Group(ctx context.Context, name string) (models.Group, error)
+}
+
+type PolicyProvider interface {
+	Policies(ctx context.Context, permissions []string, appID int) ([]models.Policy, error)
+}
+
+type RefreshTokenSaver interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	UseRefreshToken(ctx context.Context, tokenId string) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId string) error
+}
+
+type RefreshTokenProvider interface {
+	RefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+}
+
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userId string, before time.Time, ttl time.Duration) error
+}
+
+type RevokedTokenProvider interface {
+	IsTokenRevoked(ctx context.Context, tokenId string) (bool, error)
+	UserTokensRevokedBefore(ctx context.Context, userId string) (time.Time, error)
+}
+
+type KeyProvider interface {
+	SigningKey(ctx context.Context, app models.App) (jwt.SigningKey, error)
+	VerificationKey(ctx context.Context, keyId string, appID int) (jwt.SigningKey, error)
+	PublicKeys(ctx context.Context) ([]jwt.JWK, error)
+	Keys(ctx context.Context) ([]models.SigningKey, error)
+	Rotate(ctx context.Context, app models.App) (models.SigningKey, error)
+	Retire(ctx context.Context, keyId string) error
+}
+
+type MFASaver interface {
+	SaveMFAPendingSecret(ctx context.Context, userId string, secret []byte) error
+	EnableMFA(ctx context.Context,
+		userId string,
+		secret []byte,
+		step int64,
+		recoveryCodes [][]byte,
+	) error
+	UseMFAStep(ctx context.Context, userId string, step int64) error
+	SaveRecoveryCodes(ctx context.Context, userId string, recoveryCodes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userId string, recoveryCode []byte) error
+}
+
+type MFAChallengeSaver interface {
+	SaveMFAChallenge(ctx context.Context,
+		tokenHash string,
+		challenge models.MFAChallenge,
+		ttl time.Duration,
+	) error
+	IncrementMFAChallengeAttempts(ctx context.Context, tokenHash string) (int64, error)
+	DeleteMFAChallenge(ctx context.Context, tokenHash string) error
+}
+
+type MFAChallengeProvider interface {
+	MFAChallenge(ctx context.Context, tokenHash string) (models.MFAChallenge, error)
+}
+
+type AuditEventSaver interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+type OneTimeTokenSaver interface {
+	SaveOneTimeToken(ctx context.Context, token models.OneTimeToken) error
+	UseOneTimeToken(ctx context.Context, tokenHash string, purpose string) (models.OneTimeToken, error)
+	DeleteOneTimeTokens(ctx context.Context, userId string, purpose string) error
+}
+
+type OneTimeTokenProvider interface {
+	OneTimeToken(ctx context.Context, tokenHash string, purpose string) (models.OneTimeToken, error)
+}
+
+// BreachChecker looks up how often a password appeared in known data breaches.
+type BreachChecker interface {
+	Count(password string) (int, error)
+}
+
+type LoginAttemptTracker interface {
+	IncrementLoginFailures(ctx context.Context, subject string, window time.Duration) (int64, error)
+	LockLogin(ctx context.Context, subject string, duration time.Duration) error
+	LoginLockTTL(ctx context.Context, subject string) (time.Duration, error)
+	ResetLoginFailures(ctx context.Context, subject string) error
+}
+
+type RequestLimiter interface {
+	CountRequest(ctx context.Context, subject string, window time.Duration) (int64, error)
 }
 
 var (
-	ErrorInvalidCredentials = errors.New("invalid credentials")
-	ErrorUserExists         = errors.New("user exists")
-	ErrorAppNotFound        = errors.New("wrong application AppID")
-	ErrorUserNotAuthorized  = errors.New("user action is not authorized")
+	ErrorInvalidCredentials  = errors.New("invalid credentials")
+	ErrorUserExists          = errors.New("user exists")
+	ErrorAppNotFound         = errors.New("wrong application AppID")
+	ErrorUserNotAuthorized   = errors.New("user action is not authorized")
+	ErrorInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrorInvalidToken        = errors.New("invalid token")
+	ErrorUserDisabled        = errors.New("user is disabled")
+	ErrorMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrorMFANotPending       = errors.New("mfa enrollment has not been started")
+	ErrorInvalidMFACode      = errors.New("invalid mfa code")
+	ErrorInvalidMFAToken     = errors.New("invalid mfa token")
+	ErrorMFANotEnabled       = errors.New("mfa is not enabled")
+	ErrorInvalidResetToken   = errors.New("invalid password reset token")
+	ErrorEmailNotVerified    = errors.New("email is not verified")
+	ErrorInvalidVerifyToken  = errors.New("invalid email verification token")
+	ErrorAccountLocked       = errors.New("account is temporarily locked")
+	ErrorUserNotFound        = errors.New("user not found")
+	ErrorInvalidChangeToken  = errors.New("invalid email change token")
+	ErrorRoleExists          = errors.New("role already exists")
+	ErrorRoleNotFound        = errors.New("role not found")
+	ErrorInvalidPermission   = errors.New("invalid permission name")
+	ErrorGroupExists         = errors.New("group already exists")
+	ErrorGroupNotFound       = errors.New("group not found")
+	ErrorGroupCycle          = errors.New("group nesting would form a cycle")
+	ErrorTooManyRequests     = errors.New("too many requests")
 )
 
+// Deps are the storages and services the Auth service depends on.
+type Deps struct {
+	UserSaver            UserSaver
+	UserProvider         UserProvider
+	AppProvider          AppProvider
+	PermissionProvider   PermissionProvider
+	RoleSaver            RoleSaver
+	RoleProvider         RoleProvider
+	GroupSaver           GroupSaver
+	GroupProvider        GroupProvider
+	PolicyProvider       PolicyProvider
+	RefreshTokenSaver    RefreshTokenSaver
+	RefreshTokenProvider RefreshTokenProvider
+	TokenRevoker         TokenRevoker
+	RevokedTokenProvider RevokedTokenProvider
+	KeyProvider          KeyProvider
+	MFASaver             MFASaver
+	MFAChallengeSaver    MFAChallengeSaver
+	MFAChallengeProvider MFAChallengeProvider
+	AuditEventSaver      AuditEventSaver
+	OneTimeTokenSaver    OneTimeTokenSaver
+	OneTimeTokenProvider OneTimeTokenProvider
+	LoginAttemptTracker  LoginAttemptTracker
+	RequestLimiter       RequestLimiter
+	// BreachChecker may be nil, which disables the breached password check.
+	BreachChecker BreachChecker
+}
+
 // New returns a new instance of the Auth service
 func New(
 	log *slog.Logger,
-	userSaver UserSaver,
-	userProvider UserProvider,
-	appProvider AppProvider,
-	permissionProvider PermissionProvider,
-	tokenTTL time.Duration,
+	asynqClient *asynq.Client,
+	deps Deps,
+	cfg Config,
 ) *Auth {
 	return &Auth{
-		log:                log,
-		userSaver:          userSaver,
-		userProvider:       userProvider,
-		appProvider:        appProvider,
-		permissionProvider: permissionProvider,
-		tokenTTL:           tokenTTL,
+		log:                  log,
+		asynqClient:          asynqClient,
+		userSaver:            deps.UserSaver,
+		userProvider:         deps.UserProvider,
+		appProvider:          deps.AppProvider,
+		permissionProvider:   deps.PermissionProvider,
+		roleSaver:            deps.RoleSaver,
+		roleProvider:         deps.RoleProvider,
+		groupSaver:           deps.GroupSaver,
+		groupProvider:        deps.GroupProvider,
+		policyProvider:       deps.PolicyProvider,
+		refreshTokenSaver:    deps.RefreshTokenSaver,
+		refreshTokenProvider: deps.RefreshTokenProvider,
+		tokenRevoker:         deps.TokenRevoker,
+		revokedTokenProvider: deps.RevokedTokenProvider,
+		keyProvider:          deps.KeyProvider,
+		mfaSaver:             deps.MFASaver,
+		mfaChallengeSaver:    deps.MFAChallengeSaver,
+		mfaChallengeProvider: deps.MFAChallengeProvider,
+		auditEventSaver:      deps.AuditEventSaver,
+		oneTimeTokenSaver:    deps.OneTimeTokenSaver,
+		oneTimeTokenProvider: deps.OneTimeTokenProvider,
+		loginAttemptTracker:  deps.LoginAttemptTracker,
+		requestLimiter:       deps.RequestLimiter,
+		breachChecker:        deps.BreachChecker,
+		cfg:                  cfg,
 	}
 }
 
-// Login checks if user with given credentials exists in the system and returns access token.
+// Login checks if user with given credentials exists in the system and returns access and refresh tokens.
+// Every login starts a new refresh token family.
+//
+// Users with MFA enabled get an MFA token instead, the login is completed by VerifyMFA.
 //
 // If user exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error
+// If there were too many failed logins of the account or from the client IP, returns error.
 func (a *Auth) Login(
 	ctx context.Context,
 	email string,
 	password string,
 	appID int,
-) (string, error) {
+	clientIP string,
+) (models.LoginResult, error) {
 	const op = "auth.Login"
 
 	log := a.log.With(
@@ -84,48 +350,103 @@ func (a *Auth) Login(
 
 	log.Info("Logging user")
 
+	if err := a.checkLoginLock(ctx, log, email, clientIP); err != nil {
+		if errors.Is(err, ErrorAccountLocked) {
+			return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to check login lock", slog.String("error", err.Error()))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	user, err := a.userProvider.User(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrorUserNotFound) {
 			a.log.Warn("user not found", slog.String("error", err.Error()))
 
-			return "", fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+			if err := a.recordLoginFailure(ctx, log, email, "", clientIP); err != nil {
+				log.Error("failed to record login failure", slog.String("error", err.Error()))
+			}
+
+			return models.LoginResult{}, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
 		}
 
 		a.log.Error("failed to get user", slog.String("error", err.Error()))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials", slog.String("error", err.Error()))
+	if ok, err := hasher.Verify(user.PasswordHash, password); !ok {
+		if err != nil {
+			log.Error("failed to verify password hash", slog.String("userId", user.UniqueId), slog.String("error", err.Error()))
+		}
+
+		a.log.Info("invalid credentials")
+
+		if err := a.recordLoginFailure(ctx, log, email, user.UniqueId, clientIP); err != nil {
+			log.Error("failed to record login failure", slog.String("error", err.Error()))
+		}
 
-		return "", fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
 	}
 
+	if user.Disabled {
+		log.Warn("user is disabled", slog.String("userId", user.UniqueId))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, ErrorUserDisabled)
+	}
+
+	a.rehashPassword(ctx, log, user, password)
+
 	app, err := a.appProvider.App(ctx, appID)
 	if err != nil {
 		if errors.Is(err, storage.ErrorAppNotFound) {
-			return "", fmt.Errorf("%s: %w", op, ErrorAppNotFound)
+			return models.LoginResult{}, fmt.Errorf("%s: %w", op, ErrorAppNotFound)
 		}
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if app.RequireVerifiedEmail && !user.EmailVerified {
+		log.Warn("email is not verified", slog.String("userId", user.UniqueId))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, ErrorEmailNotVerified)
+	}
+
+	if user.MFA.Enabled {
+		mfaToken, err := a.startMFAChallenge(ctx, user, app)
+		if err != nil {
+			log.Error("failed to start mfa challenge", slog.String("error", err.Error()))
+
+			return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("password accepted, waiting for second factor")
+
+		return models.LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	if err := a.loginAttemptTracker.ResetLoginFailures(ctx, accountSubject(email)); err != nil {
+		log.Error("failed to reset login failures", slog.String("error", err.Error()))
 	}
 
 	log.Info("user logged in successfully")
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	tokens, err := a.issueTokens(ctx, user, app, uuid.New().String())
 	if err != nil {
-		a.log.Error("failed to generate token", slog.String("error", err.Error()))
+		a.log.Error("failed to generate tokens", slog.String("error", err.Error()))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
+	return models.LoginResult{Tokens: tokens}, nil
 }
 
 // RegisterNewUser registers new user in the system and returns user AppID
 // If user with given email address already exists, returns error.
+// If the password violates the password policy, returns a *passwordpolicy.Error.
+// A link verifying the email address is sent to the user, if it can't be dispatched, returns error.
 func (a *Auth) RegisterNewUser(
 	ctx context.Context,
 	email string,
@@ -139,7 +460,18 @@ func (a *Auth) RegisterNewUser(
 
 	log.Info("Registering user")
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := a.validatePassword(password, models.User{Email: email}); err != nil {
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			log.Info("password rejected by the password policy")
+		} else {
+			log.Error("failed to validate password", slog.String("error", err.Error()))
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	passwordHash, err := a.cfg.PasswordHasher.Hash(password)
 
 	if err != nil {
 		log.Error("Failed to generate password hash", slog.String("error", err.Error()))
@@ -163,19 +495,39 @@ func (a *Auth) RegisterNewUser(
 
 	log.Info("User registered")
 
+	// The user exists at this point, if the email can't be dispatched it can be requested again
+	// with ResendVerificationEmail.
+	if err := a.sendVerificationEmail(ctx, id, email); err != nil {
+		log.Error("Failed to dispatch verification email", slog.String("error", err.Error()))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (a *Auth) Authorize(ctx context.Context, permission string, userId string) (isAuthorized bool, err error) {
+// Authorize reports whether the user holds the permission for the app.
+// Permissions granted for all apps count for every app.
+//
+// Policies which apply to the permission are evaluated against the attributes on top of the grants,
+// see applyPolicies.
+func (a *Auth) Authorize(
+	ctx context.Context,
+	permission string,
+	userId string,
+	appID int,
+	attributes models.AuthorizationAttributes,
+) (isAuthorized bool, err error) {
 	const op = "auth.Authorize"
 
 	log := a.log.With(
 		slog.String("op", op),
+		slog.Int("appId", appID),
 	)
 
 	log.Info("Authorizing user action")
 
-	can, err := a.permissionProvider.Can(ctx, permission, userId)
+	can, err := a.permissionProvider.Can(ctx, permission, userId, appID)
 
 	if err != nil {
 		log.Error("failed to authorize user", slog.String("error", err.Error()))
@@ -183,5 +535,50 @@ func (a *Auth) Authorize(ctx context.Context, permission string, userId string)
 		return false, fmt.Errorf("%s: %w", op, ErrorUserNotAuthorized)
 	}
 
-	return can, nil
+	checks := []models.PermissionCheck{{UserId: userId, Permission: permission}}
+
+	results, err := a.applyPolicies(ctx, log, checks, []bool{can}, appID, attributes)
+	if err != nil {
+		log.Error("failed to apply policies", slog.String("error", err.Error()))
+
+		return false, fmt.Errorf("%s: %w", op, ErrorUserNotAuthorized)
+	}
+
+	return results[0], nil
+}
+
+// BatchAuthorize evaluates several permission checks for the app like Authorize does,
+// the attributes are shared by all checks. The results are in the order of the checks.
+func (a *Auth) BatchAuthorize(
+	ctx context.Context,
+	checks []models.PermissionCheck,
+	appID int,
+	attributes models.AuthorizationAttributes,
+) ([]bool, error) {
+	const op = "auth.BatchAuthorize"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", appID),
+		slog.Int("checks", len(checks)),
+	)
+
+	log.Info("Authorizing user actions")
+
+	granted, err := a.permissionProvider.CanBatch(ctx, checks, appID)
+
+	if err != nil {
+		log.Error("failed to authorize user actions", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, ErrorUserNotAuthorized)
+	}
+
+	results, err := a.applyPolicies(ctx, log, checks, granted, appID, attributes)
+	if err != nil {
+		log.Error("failed to apply policies", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, ErrorUserNotAuthorized)
+	}
+
+	return results, nil
 }
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"auth-sso/internal/tasks/handlers/email"
+	"auth-sso/lib/mail"
+	"context"
+	"encoding/json"
+	"github.com/hibiken/asynq"
+	"net/url"
+)
+
+// sendEmail dispatches an async job delivering the message.
+func (a *Auth) sendEmail(ctx context.Context, message mail.Message) error {
+	payload, err := json.Marshal(email.SendTaskPayload{Message: message})
+	if err != nil {
+		return err
+	}
+
+	task := asynq.NewTask(email.TaskIdentifier, payload)
+	if _, err := a.asynqClient.EnqueueContext(ctx, task); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// linkWithToken adds the token to the link as the "token" query parameter.
+// Without a configured link only the token itself is returned.
+func linkWithToken(link string, token string) string {
+	if link == "" {
+		return token
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return token
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/hasher"
+	"auth-sso/lib/mail"
+	"auth-sso/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ChangeEmail starts changing the email address of the user after checking the password.
+//
+// A confirmation link is sent to the new address, the address is only replaced by ConfirmEmailChange.
+// The current address is notified about the request.
+func (a *Auth) ChangeEmail(ctx context.Context, userId string, password string, newEmail string) error {
+	const op = "auth.ChangeEmail"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ok, err := hasher.Verify(user.PasswordHash, password); !ok {
+		if err != nil {
+			log.Error("failed to verify password hash", slog.String("error", err.Error()))
+		}
+
+		log.Info("invalid password")
+
+		return fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+	}
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return fmt.Errorf("%s: %w", op, ErrorUserExists)
+	}
+
+	_, err = a.userProvider.User(ctx, newEmail)
+	if err == nil {
+		log.Info("email address is taken")
+
+		return fmt.Errorf("%s: %w", op, ErrorUserExists)
+	}
+
+	if !errors.Is(err, storage.ErrorUserNotFound) {
+		log.Error("failed to look up email address", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.oneTimeTokenSaver.DeleteOneTimeTokens(ctx, user.UniqueId, models.OneTimeTokenPurposeEmailChange); err != nil {
+		log.Error("failed to delete previous email change tokens", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := opaque.NewToken()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
+	err = a.oneTimeTokenSaver.SaveOneTimeToken(ctx, models.OneTimeToken{
+		TokenId:   uuid.New().String(),
+		UserId:    user.UniqueId,
+		Purpose:   models.OneTimeTokenPurposeEmailChange,
+		TokenHash: opaque.Hash(token),
+		Email:     newEmail,
+		ExpiresAt: now.Add(a.cfg.EmailChangeTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		log.Error("failed to save email change token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.sendEmail(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: "Please confirm your new email address with the following link, it expires in " +
+			a.cfg.EmailChangeTTL.String() + ":\n\n" +
+			linkWithToken(a.cfg.EmailChangeURL, token) + "\n\n" +
+			"If you did not request this change, you can ignore this email.\n",
+	})
+	if err != nil {
+		log.Error("failed to dispatch email change confirmation", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.sendEmail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is about to change",
+		Body: "A change of the email address of your account to " + newEmail + " was requested.\n\n" +
+			"If you did not request this change, change your password right away.\n",
+	})
+	if err != nil {
+		log.Error("failed to dispatch email change notice", slog.String("error", err.Error()))
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventEmailChangeRequested,
+		UserId:  user.UniqueId,
+		Details: map[string]string{"newEmail": newEmail},
+	})
+
+	log.Info("email change requested")
+
+	return nil
+}
+
+// ConfirmEmailChange replaces the email address of the user with the address the token was sent to.
+func (a *Auth) ConfirmEmailChange(ctx context.Context, token string) error {
+	const op = "auth.ConfirmEmailChange"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	changeToken, err := a.oneTimeTokenSaver.UseOneTimeToken(ctx, opaque.Hash(token), models.OneTimeTokenPurposeEmailChange)
+	if err != nil {
+		if errors.Is(err, storage.ErrorOneTimeTokenNotFound) {
+			log.Warn("invalid email change token")
+
+			return fmt.Errorf("%s: %w", op, ErrorInvalidChangeToken)
+		}
+
+		log.Error("failed to use email change token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", changeToken.UserId))
+
+	if err := a.userSaver.UpdateEmail(ctx, changeToken.UserId, changeToken.Email); err != nil {
+		if errors.Is(err, storage.ErrorUserExists) {
+			log.Info("email address was taken in the meantime")
+
+			return fmt.Errorf("%s: %w", op, ErrorUserExists)
+		}
+
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorInvalidChangeToken)
+		}
+
+		log.Error("failed to update email", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventEmailChanged,
+		UserId:  changeToken.UserId,
+		Details: map[string]string{"newEmail": changeToken.Email},
+	})
+
+	log.Info("email changed")
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/permission"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CreateGroup creates a new empty group.
+func (a *Auth) CreateGroup(ctx context.Context, name string) (models.Group, error) {
+	const op = "auth.CreateGroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", name),
+	)
+
+	group := models.Group{
+		Name:        name,
+		Permissions: []models.Permission{},
+		CreatedAt:   time.Now(),
+	}
+
+	if err := a.groupSaver.SaveGroup(ctx, group); err != nil {
+		if errors.Is(err, storage.ErrorGroupExists) {
+			return models.Group{}, fmt.Errorf("%s: %w", op, ErrorGroupExists)
+		}
+
+		log.Error("failed to save group", slog.String("error", err.Error()))
+
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("group created")
+
+	return group, nil
+}
+
+// AddUserToGroup makes the user a member of the group, granting it the permissions and roles of the group
+// and of all groups the group is nested in.
+func (a *Auth) AddUserToGroup(ctx context.Context, groupName string, userId string) error {
+	const op = "auth.AddUserToGroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("userId", userId),
+	)
+
+	if err := a.checkGroup(ctx, groupName); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.groupSaver.AddUserToGroup(ctx, userId, groupName); err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to add user to group", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventGroupJoined,
+		UserId:  userId,
+		Details: map[string]string{"group": groupName},
+	})
+
+	log.Info("user added to group")
+
+	return nil
+}
+
+// RemoveUserFromGroup removes the direct membership of the user in the group.
+// The user stays a member through groups nested in the group.
+func (a *Auth) RemoveUserFromGroup(ctx context.Context, groupName string, userId string) error {
+	const op = "auth.RemoveUserFromGroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("userId", userId),
+	)
+
+	if err := a.groupSaver.RemoveUserFromGroup(ctx, userId, groupName); err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to remove user from group", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventGroupLeft,
+		UserId:  userId,
+		Details: map[string]string{"group": groupName},
+	})
+
+	log.Info("user removed from group")
+
+	return nil
+}
+
+// AddSubgroup nests the subgroup in the group, its members become members of the group.
+// Returns ErrorGroupCycle if the group is already nested in the subgroup.
+func (a *Auth) AddSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "auth.AddSubgroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("subgroup", subgroupName),
+	)
+
+	if err := a.groupSaver.AddSubgroup(ctx, groupName, subgroupName); err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		if errors.Is(err, storage.ErrorGroupCycle) {
+			log.Info("group nesting rejected, it would form a cycle")
+
+			return fmt.Errorf("%s: %w", op, ErrorGroupCycle)
+		}
+
+		log.Error("failed to add subgroup", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("subgroup added")
+
+	return nil
+}
+
+func (a *Auth) RemoveSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "auth.RemoveSubgroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("subgroup", subgroupName),
+	)
+
+	if err := a.groupSaver.RemoveSubgroup(ctx, groupName, subgroupName); err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		log.Error("failed to remove subgroup", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("subgroup removed")
+
+	return nil
+}
+
+// GrantGroupPermission adds the permission for the app to the group, granting it to all members of the group.
+// With deny set the permission is denied instead.
+func (a *Auth) GrantGroupPermission(
+	ctx context.Context,
+	groupName string,
+	name string,
+	appID int,
+	deny bool,
+) error {
+	const op = "auth.GrantGroupPermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("permission", name),
+		slog.Int("appId", appID),
+		slog.Bool("deny", deny),
+	)
+
+	if err := permission.Validate(name); err != nil {
+		return fmt.Errorf("%s: %w", op, ErrorInvalidPermission)
+	}
+
+	if err := a.checkAppScope(ctx, appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := a.groupSaver.GrantGroupPermission(ctx, groupName, models.Permission{Name: name, AppID: appID, Deny: deny})
+	if err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		log.Error("failed to grant permission", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("permission granted")
+
+	return nil
+}
+
+// RevokeGroupPermission removes the allow or deny grant of the permission for the app from the group.
+func (a *Auth) RevokeGroupPermission(ctx context.Context, groupName string, name string, appID int) error {
+	const op = "auth.RevokeGroupPermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("permission", name),
+		slog.Int("appId", appID),
+	)
+
+	err := a.groupSaver.RevokeGroupPermission(ctx, groupName, models.Permission{Name: name, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		log.Error("failed to revoke permission", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("permission revoked")
+
+	return nil
+}
+
+// AssignGroupRole assigns the role to the group for the app, or for all apps if appID is models.AppIDAll.
+func (a *Auth) AssignGroupRole(ctx context.Context, groupName string, roleName string, appID int) error {
+	const op = "auth.AssignGroupRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("role", roleName),
+		slog.Int("appId", appID),
+	)
+
+	if err := a.checkAppScope(ctx, appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.roleProvider.Role(ctx, roleName); err != nil {
+		if errors.Is(err, storage.ErrorRoleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorRoleNotFound)
+		}
+
+		log.Error("failed to get role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := a.groupSaver.AssignGroupRole(ctx, groupName, models.RoleAssignment{Role: roleName, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		log.Error("failed to assign role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+// UnassignGroupRole removes the assignment of the role for the app from the group.
+func (a *Auth) UnassignGroupRole(ctx context.Context, groupName string, roleName string, appID int) error {
+	const op = "auth.UnassignGroupRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("role", roleName),
+		slog.Int("appId", appID),
+	)
+
+	err := a.groupSaver.UnassignGroupRole(ctx, groupName, models.RoleAssignment{Role: roleName, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorGroupNotFound)
+		}
+
+		log.Error("failed to unassign role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role unassigned")
+
+	return nil
+}
+
+// checkGroup returns ErrorGroupNotFound unless the group exists.
+func (a *Auth) checkGroup(ctx context.Context, groupName string) error {
+	if _, err := a.groupProvider.Group(ctx, groupName); err != nil {
+		if errors.Is(err, storage.ErrorGroupNotFound) {
+			return ErrorGroupNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Introspect reports whether the token is currently active, following RFC 7662.
+//
+// A token is inactive if it is invalid, expired or revoked, or if its user
+// no longer exists or has been disabled. Inactive tokens are not an error.
+func (a *Auth) Introspect(ctx context.Context, token string) (models.Introspection, error) {
+	const op = "auth.Introspect"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := a.Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrorInvalidToken) {
+			return models.Introspection{Active: false}, nil
+		}
+
+		return models.Introspection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userProvider.UserById(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			log.Info("token user not found", slog.String("userId", claims.UserID))
+
+			return models.Introspection{Active: false}, nil
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return models.Introspection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Info("token user is disabled", slog.String("userId", claims.UserID))
+
+		return models.Introspection{Active: false}, nil
+	}
+
+	return models.Introspection{
+		Active:    true,
+		TokenId:   claims.ID,
+		UserId:    claims.UserID,
+		Email:     claims.Email,
+		AppID:     claims.AppID,
+		Scopes:    claims.Scopes,
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		IssuedAt:  claims.IssuedAt,
+		NotBefore: claims.NotBefore,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Jwks returns the public keys resource servers can verify tokens with.
+func (a *Auth) Jwks(ctx context.Context) ([]jwt.JWK, error) {
+	const op = "auth.Jwks"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	keys, err := a.keyProvider.PublicKeys(ctx)
+	if err != nil {
+		log.Error("failed to get public keys", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// SigningKeys lists all signing keys without their private key material.
+func (a *Auth) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "auth.SigningKeys"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	keys, err := a.keyProvider.Keys(ctx)
+	if err != nil {
+		log.Error("failed to list signing keys", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKey replaces the active signing key of the app.
+// App ID zero rotates the key shared by all apps without their own signing algorithm.
+func (a *Auth) RotateSigningKey(ctx context.Context, appID int) (models.SigningKey, error) {
+	const op = "auth.RotateSigningKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", appID),
+	)
+
+	log.Info("Rotating signing key")
+
+	var app models.App
+
+	if appID != 0 {
+		var err error
+
+		app, err = a.appProvider.App(ctx, appID)
+		if err != nil {
+			if errors.Is(err, storage.ErrorAppNotFound) {
+				return models.SigningKey{}, fmt.Errorf("%s: %w", op, ErrorAppNotFound)
+			}
+
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	key, err := a.keyProvider.Rotate(ctx, app)
+	if err != nil {
+		log.Error("failed to rotate signing key", slog.String("error", err.Error()))
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Signing key rotated", slog.String("keyId", key.KeyId))
+
+	return key, nil
+}
+
+// RetireSigningKey stops a rotated key from being used for verification before its overlap window ends.
+func (a *Auth) RetireSigningKey(ctx context.Context, keyId string) error {
+	const op = "auth.RetireSigningKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("keyId", keyId),
+	)
+
+	if err := a.keyProvider.Retire(ctx, keyId); err != nil {
+		log.Warn("failed to retire signing key", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// LockoutConfig controls the brute-force protection of Login and VerifyMFA.
+//
+// Failed logins, wrong passwords and wrong second factors alike, are counted per account and
+// per client IP. Once a threshold is reached, logins of the account or from the IP are locked
+// for BaseDelay, doubling with every further failure up to MaxDelay. A zero threshold disables the respective check.
+type LockoutConfig struct {
+	// Window is how long failures are remembered after the last one.
+	Window           time.Duration
+	AccountThreshold int
+	IPThreshold      int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+}
+
+// UnlockAccount lifts the login lock of the account and forgets its failed logins.
+func (a *Auth) UnlockAccount(ctx context.Context, email string) error {
+	const op = "auth.UnlockAccount"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := a.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", user.UniqueId))
+
+	if err := a.loginAttemptTracker.ResetLoginFailures(ctx, accountSubject(user.Email)); err != nil {
+		log.Error("failed to reset login failures", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventAccountUnlocked,
+		UserId: user.UniqueId,
+	})
+
+	log.Info("account unlocked")
+
+	return nil
+}
+
+// checkLoginLock returns ErrorAccountLocked if logins of the account or from the client IP are locked.
+func (a *Auth) checkLoginLock(ctx context.Context, log *slog.Logger, email string, clientIP string) error {
+	subjects := []string{accountSubject(email)}
+	if clientIP != "" {
+		subjects = append(subjects, ipSubject(clientIP))
+	}
+
+	for _, subject := range subjects {
+		ttl, err := a.loginAttemptTracker.LoginLockTTL(ctx, subject)
+		if err != nil {
+			return err
+		}
+
+		if ttl > 0 {
+			log.Warn("login is locked", slog.String("subject", subject), slog.Duration("remaining", ttl))
+
+			return ErrorAccountLocked
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts a failed login of the account and from the client IP
+// and locks them once their threshold is reached. userId is empty for unknown accounts.
+func (a *Auth) recordLoginFailure(
+	ctx context.Context,
+	log *slog.Logger,
+	email string,
+	userId string,
+	clientIP string,
+) error {
+	lockout := a.cfg.Lockout
+
+	locked, err := a.countLoginFailure(ctx, log, accountSubject(email), lockout.AccountThreshold)
+	if err != nil {
+		return err
+	}
+
+	if locked && userId != "" {
+		a.saveAuditEvent(ctx, log, models.AuditEvent{
+			Type:    models.AuditEventAccountLocked,
+			UserId:  userId,
+			Details: map[string]string{"clientIp": clientIP},
+		})
+	}
+
+	if clientIP == "" {
+		return nil
+	}
+
+	if _, err := a.countLoginFailure(ctx, log, ipSubject(clientIP), lockout.IPThreshold); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// countLoginFailure counts a failure of the subject and reports whether the subject got locked.
+func (a *Auth) countLoginFailure(ctx context.Context, log *slog.Logger, subject string, threshold int) (bool, error) {
+	if threshold <= 0 {
+		return false, nil
+	}
+
+	failures, err := a.loginAttemptTracker.IncrementLoginFailures(ctx, subject, a.cfg.Lockout.Window)
+	if err != nil {
+		return false, err
+	}
+
+	if failures < int64(threshold) {
+		return false, nil
+	}
+
+	delay := lockoutDelay(failures-int64(threshold), a.cfg.Lockout.BaseDelay, a.cfg.Lockout.MaxDelay)
+
+	if err := a.loginAttemptTracker.LockLogin(ctx, subject, delay); err != nil {
+		return false, err
+	}
+
+	log.Warn("too many failed logins, locking",
+		slog.String("subject", subject),
+		slog.Int64("failures", failures),
+		slog.Duration("duration", delay),
+	)
+
+	return true, nil
+}
+
+// lockoutDelay doubles the base delay for every failure past the threshold, capped at max.
+func lockoutDelay(excess int64, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+
+	for i := int64(0); i < excess && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func ipSubject(clientIP string) string {
+	return "ip:" + clientIP
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeAttempts tracks login failures and locks in memory, locks never expire.
+type fakeAttempts struct {
+	failures map[string]int64
+	locks    map[string]time.Duration
+}
+
+func newFakeAttempts() *fakeAttempts {
+	return &fakeAttempts{failures: map[string]int64{}, locks: map[string]time.Duration{}}
+}
+
+func (f *fakeAttempts) IncrementLoginFailures(_ context.Context, subject string, _ time.Duration) (int64, error) {
+	f.failures[subject]++
+
+	return f.failures[subject], nil
+}
+
+func (f *fakeAttempts) LockLogin(_ context.Context, subject string, duration time.Duration) error {
+	f.locks[subject] = duration
+
+	return nil
+}
+
+func (f *fakeAttempts) LoginLockTTL(_ context.Context, subject string) (time.Duration, error) {
+	return f.locks[subject], nil
+}
+
+func (f *fakeAttempts) ResetLoginFailures(_ context.Context, subject string) error {
+	delete(f.failures, subject)
+	delete(f.locks, subject)
+
+	return nil
+}
+
+type fakeAuditLog []models.AuditEvent
+
+func (f *fakeAuditLog) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	*f = append(*f, event)
+
+	return nil
+}
+
+func TestLockoutDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		excess int64
+		base   time.Duration
+		max    time.Duration
+		want   time.Duration
+	}{
+		{name: "at the threshold", excess: 0, base: time.Second, max: time.Minute, want: time.Second},
+		{name: "one past", excess: 1, base: time.Second, max: time.Minute, want: 2 * time.Second},
+		{name: "three past", excess: 3, base: time.Second, max: time.Minute, want: 8 * time.Second},
+		{name: "reaches the cap", excess: 6, base: time.Second, max: time.Minute, want: time.Minute},
+		{name: "far past the cap", excess: 1000, base: time.Second, max: time.Minute, want: time.Minute},
+		{name: "base above the cap", excess: 0, base: time.Hour, max: time.Minute, want: time.Minute},
+		{name: "base equals the cap", excess: 2, base: time.Minute, max: time.Minute, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lockoutDelay(tt.excess, tt.base, tt.max); got != tt.want {
+				t.Fatalf("lockoutDelay(%d, %v, %v) = %v, want %v", tt.excess, tt.base, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	const (
+		email    = "Alice@Example.com"
+		clientIP = "203.0.113.7"
+	)
+
+	account := accountSubject(email)
+	ip := ipSubject(clientIP)
+
+	lockout := LockoutConfig{
+		Window:           time.Hour,
+		AccountThreshold: 3,
+		IPThreshold:      5,
+		BaseDelay:        time.Second,
+		MaxDelay:         4 * time.Second,
+	}
+
+	tests := []struct {
+		name        string
+		lockout     LockoutConfig
+		clientIP    string
+		failures    int
+		wantAccount time.Duration
+		wantIP      time.Duration
+	}{
+		{name: "below both thresholds", lockout: lockout, clientIP: clientIP, failures: 2},
+		{name: "account threshold", lockout: lockout, clientIP: clientIP, failures: 3, wantAccount: time.Second},
+		{name: "delay doubles", lockout: lockout, clientIP: clientIP, failures: 4, wantAccount: 2 * time.Second},
+		{
+			name:        "ip threshold",
+			lockout:     lockout,
+			clientIP:    clientIP,
+			failures:    5,
+			wantAccount: 4 * time.Second,
+			wantIP:      time.Second,
+		},
+		{
+			name:        "delay capped",
+			lockout:     lockout,
+			clientIP:    clientIP,
+			failures:    10,
+			wantAccount: 4 * time.Second,
+			wantIP:      4 * time.Second,
+		},
+		{name: "no client ip", lockout: lockout, failures: 5, wantAccount: 4 * time.Second},
+		{
+			name:     "account check disabled",
+			lockout:  LockoutConfig{IPThreshold: 5, BaseDelay: time.Second, MaxDelay: time.Minute},
+			clientIP: clientIP,
+			failures: 5,
+			wantIP:   time.Second,
+		},
+		{
+			name:        "ip check disabled",
+			lockout:     LockoutConfig{AccountThreshold: 3, BaseDelay: time.Second, MaxDelay: time.Minute},
+			clientIP:    clientIP,
+			failures:    5,
+			wantAccount: 4 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := newFakeAttempts()
+			audit := &fakeAuditLog{}
+
+			a := &Auth{
+				loginAttemptTracker: attempts,
+				auditEventSaver:     audit,
+				cfg:                 Config{Lockout: tt.lockout},
+			}
+
+			for i := 0; i < tt.failures; i++ {
+				if err := a.recordLoginFailure(context.Background(), testLogger(), email, "u1", tt.clientIP); err != nil {
+					t.Fatalf("recordLoginFailure() = %v", err)
+				}
+			}
+
+			if got := attempts.locks[account]; got != tt.wantAccount {
+				t.Errorf("account lock = %v, want %v", got, tt.wantAccount)
+			}
+
+			if got := attempts.locks[ip]; got != tt.wantIP {
+				t.Errorf("ip lock = %v, want %v", got, tt.wantIP)
+			}
+
+			if _, ok := attempts.failures[ip]; ok && (tt.clientIP == "" || tt.lockout.IPThreshold == 0) {
+				t.Errorf("ip failures counted, want them ignored")
+			}
+
+			wantEvents := 0
+			if tt.lockout.AccountThreshold > 0 && tt.failures >= tt.lockout.AccountThreshold {
+				wantEvents = tt.failures - tt.lockout.AccountThreshold + 1
+			}
+
+			if len(*audit) != wantEvents {
+				t.Errorf("audit events = %d, want %d", len(*audit), wantEvents)
+			}
+		})
+	}
+}
+
+func TestRecordLoginFailureUnknownAccount(t *testing.T) {
+	attempts := newFakeAttempts()
+	audit := &fakeAuditLog{}
+
+	a := &Auth{
+		loginAttemptTracker: attempts,
+		auditEventSaver:     audit,
+		cfg: Config{Lockout: LockoutConfig{
+			AccountThreshold: 1,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Minute,
+		}},
+	}
+
+	if err := a.recordLoginFailure(context.Background(), testLogger(), "nobody@example.com", "", ""); err != nil {
+		t.Fatalf("recordLoginFailure() = %v", err)
+	}
+
+	if attempts.locks[accountSubject("nobody@example.com")] != time.Second {
+		t.Fatal("unknown account wasn't locked")
+	}
+
+	if len(*audit) != 0 {
+		t.Fatalf("audit events = %d, want none for an unknown account", len(*audit))
+	}
+}
+
+func TestCheckLoginLock(t *testing.T) {
+	const (
+		email    = "alice@example.com"
+		clientIP = "203.0.113.7"
+	)
+
+	tests := []struct {
+		name     string
+		locked   string
+		clientIP string
+		wantErr  error
+	}{
+		{name: "nothing locked", clientIP: clientIP},
+		{name: "account locked", locked: accountSubject(email), clientIP: clientIP, wantErr: ErrorAccountLocked},
+		{name: "account locked in other case", locked: accountSubject("ALICE@example.com"), wantErr: ErrorAccountLocked},
+		{name: "ip locked", locked: ipSubject(clientIP), clientIP: clientIP, wantErr: ErrorAccountLocked},
+		{name: "other ip locked", locked: ipSubject("198.51.100.1"), clientIP: clientIP},
+		{name: "no client ip", locked: ipSubject(clientIP)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := newFakeAttempts()
+			if tt.locked != "" {
+				attempts.locks[tt.locked] = time.Minute
+			}
+
+			a := &Auth{loginAttemptTracker: attempts}
+
+			if err := a.checkLoginLock(context.Background(), testLogger(), email, tt.clientIP); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkLoginLock() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/jwt"
+	"auth-sso/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Logout revokes the given access token until it expires.
+// If a refresh token is passed as well, its whole token family is revoked,
+// so the session can not be continued with a refresh either.
+func (a *Auth) Logout(ctx context.Context, token string, refreshToken string) error {
+	const op = "auth.Logout"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("Logging out user")
+
+	claims, err := a.parseToken(ctx, token)
+	if err != nil {
+		log.Warn("invalid access token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, ErrorInvalidToken)
+	}
+
+	if err := a.tokenRevoker.RevokeToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke access token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refreshToken == "" {
+		log.Info("user logged out", slog.String("userId", claims.UserID))
+
+		return nil
+	}
+
+	stored, err := a.refreshTokenProvider.RefreshToken(ctx, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrorRefreshTokenNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+		}
+
+		log.Error("failed to get refresh token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stored.UserId != claims.UserID {
+		log.Warn("refresh token belongs to another user", slog.String("userId", claims.UserID))
+
+		return fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+	}
+
+	if err := a.refreshTokenSaver.RevokeRefreshTokenFamily(ctx, stored.FamilyId); err != nil {
+		log.Error("failed to revoke refresh token family", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged out", slog.String("userId", claims.UserID))
+
+	return nil
+}
+
+// parseToken validates an access token issued by the service and returns its claims.
+//
+// Tokens without a key ID are checked against the current signing key of the app,
+// so a token signed with the app secret is rejected once the app moved to an asymmetric algorithm.
+// Tokens have to be issued by the service for the audience of their app.
+func (a *Auth) parseToken(ctx context.Context, token string) (jwt.Claims, error) {
+	var app models.App
+
+	claims, err := jwt.Parse(token, func(keyID string, appID int) (jwt.SigningKey, error) {
+		var err error
+
+		app, err = a.appProvider.App(ctx, appID)
+		if err != nil {
+			return jwt.SigningKey{}, err
+		}
+
+		if keyID != "" {
+			return a.keyProvider.VerificationKey(ctx, keyID, appID)
+		}
+
+		return a.keyProvider.SigningKey(ctx, app)
+	})
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	err = claims.Validate(jwt.VerifyOptions{
+		AppID:    app.AppID,
+		Issuer:   a.cfg.Issuer,
+		Audience: a.audience(app),
+	})
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// audience returns the aud claim tokens of the app are issued for.
+func (a *Auth) audience(app models.App) []string {
+	if len(app.Audience) > 0 {
+		return app.Audience
+	}
+
+	return a.cfg.Audience
+}
@@ -0,0 +1,426 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/encryption"
+	"auth-sso/lib/opaque"
+	"auth-sso/lib/recoverycode"
+	"auth-sso/lib/totp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// EnrollMFA generates a new TOTP secret for the user.
+// The secret is only used for login once confirmed with ConfirmMFA.
+func (a *Auth) EnrollMFA(ctx context.Context, userId string) (models.MFAEnrollment, error) {
+	const op = "auth.EnrollMFA"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	log.Info("Enrolling mfa")
+
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.MFA.Enabled {
+		return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, ErrorMFAAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	encrypted, err := encryption.Encrypt(a.cfg.EncryptionKey, []byte(secret))
+	if err != nil {
+		return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.mfaSaver.SaveMFAPendingSecret(ctx, user.UniqueId, encrypted); err != nil {
+		log.Error("failed to save mfa secret", slog.String("error", err.Error()))
+
+		return models.MFAEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(a.cfg.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables MFA for the user once a code of the pending secret is provided
+// and returns the recovery codes of the user. Only the digests of the codes are stored.
+func (a *Auth) ConfirmMFA(ctx context.Context, userId string, code string) ([]string, error) {
+	const op = "auth.ConfirmMFA"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.MFA.Enabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrorMFAAlreadyEnabled)
+	}
+
+	if len(user.MFA.PendingSecret) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrorMFANotPending)
+	}
+
+	secret, err := encryption.Decrypt(a.cfg.EncryptionKey, user.MFA.PendingSecret)
+	if err != nil {
+		log.Error("failed to decrypt mfa secret", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	step, ok := totp.Validate(code, string(secret), time.Now())
+	if !ok {
+		log.Warn("invalid mfa code")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrorInvalidMFACode)
+	}
+
+	recoveryCodes, digests, err := a.generateRecoveryCodes()
+	if err != nil {
+		log.Error("failed to generate recovery codes", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.mfaSaver.EnableMFA(ctx, user.UniqueId, user.MFA.PendingSecret, step, digests); err != nil {
+		log.Error("failed to enable mfa", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventMFAEnabled,
+		UserId: user.UniqueId,
+	})
+
+	log.Info("mfa enabled")
+
+	return recoveryCodes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user with a new set.
+// Codes handed out before can no longer be used.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userId string) ([]string, error) {
+	const op = "auth.RegenerateRecoveryCodes"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.MFA.Enabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrorMFANotEnabled)
+	}
+
+	recoveryCodes, digests, err := a.generateRecoveryCodes()
+	if err != nil {
+		log.Error("failed to generate recovery codes", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.mfaSaver.SaveRecoveryCodes(ctx, user.UniqueId, digests); err != nil {
+		log.Error("failed to save recovery codes", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventRecoveryCodesRegenerated,
+		UserId: user.UniqueId,
+	})
+
+	log.Info("recovery codes regenerated")
+
+	return recoveryCodes, nil
+}
+
+// VerifyMFA completes a login started by Login with the second factor
+// and returns access and refresh tokens.
+//
+// The second factor is either a TOTP code or, if recoveryCode is set, one of the user's recovery codes.
+// Wrong codes count as failed logins of the account and from the client IP, the same as wrong passwords.
+func (a *Auth) VerifyMFA(
+	ctx context.Context,
+	mfaToken string,
+	code string,
+	recoveryCode string,
+	clientIP string,
+) (models.TokenPair, error) {
+	const op = "auth.VerifyMFA"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	tokenHash := opaque.Hash(mfaToken)
+
+	challenge, err := a.mfaChallengeProvider.MFAChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrorMFAChallengeNotFound) {
+			log.Warn("mfa challenge not found")
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidMFAToken)
+		}
+
+		log.Error("failed to get mfa challenge", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", challenge.UserId))
+
+	attempts, err := a.mfaChallengeSaver.IncrementMFAChallengeAttempts(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrorMFAChallengeNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidMFAToken)
+		}
+
+		log.Error("failed to count mfa attempt", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if attempts > int64(a.cfg.MFAMaxAttempts) {
+		log.Warn("too many mfa attempts, dropping challenge")
+
+		a.deleteMFAChallenge(ctx, log, tokenHash)
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidMFAToken)
+	}
+
+	user, err := a.userProvider.UserById(ctx, challenge.UserId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidMFAToken)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("user is disabled")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorUserDisabled)
+	}
+
+	if !user.MFA.Enabled {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidMFAToken)
+	}
+
+	if err := a.checkLoginLock(ctx, log, user.Email, clientIP); err != nil {
+		if errors.Is(err, ErrorAccountLocked) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to check login lock", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if recoveryCode != "" {
+		err = a.useRecoveryCode(ctx, log, user, challenge.AppID, recoveryCode)
+	} else {
+		err = a.useTOTPCode(ctx, user, code)
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrorInvalidMFACode) {
+			log.Warn("invalid mfa code")
+
+			if err := a.recordLoginFailure(ctx, log, user.Email, user.UniqueId, clientIP); err != nil {
+				log.Error("failed to record login failure", slog.String("error", err.Error()))
+			}
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to check mfa code", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.deleteMFAChallenge(ctx, log, tokenHash)
+
+	if err := a.loginAttemptTracker.ResetLoginFailures(ctx, accountSubject(user.Email)); err != nil {
+		log.Error("failed to reset login failures", slog.String("error", err.Error()))
+	}
+
+	app, err := a.appProvider.App(ctx, challenge.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorAppNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorAppNotFound)
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := a.issueTokens(ctx, user, app, uuid.New().String())
+	if err != nil {
+		log.Error("failed to generate tokens", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in successfully")
+
+	return tokens, nil
+}
+
+// useTOTPCode checks the code against the user's secret and records its time step,
+// so the code can't be replayed.
+func (a *Auth) useTOTPCode(ctx context.Context, user models.User, code string) error {
+	secret, err := encryption.Decrypt(a.cfg.EncryptionKey, user.MFA.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(code, string(secret), time.Now())
+	if !ok {
+		return ErrorInvalidMFACode
+	}
+
+	if err := a.mfaSaver.UseMFAStep(ctx, user.UniqueId, step); err != nil {
+		if errors.Is(err, storage.ErrorMFACodeUsed) {
+			return ErrorInvalidMFACode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// useRecoveryCode consumes the recovery code, the code is looked up by its digest.
+func (a *Auth) useRecoveryCode(
+	ctx context.Context,
+	log *slog.Logger,
+	user models.User,
+	appID int,
+	recoveryCode string,
+) error {
+	digest := recoverycode.Digest(a.cfg.EncryptionKey, recoveryCode)
+
+	if err := a.mfaSaver.UseRecoveryCode(ctx, user.UniqueId, digest); err != nil {
+		if errors.Is(err, storage.ErrorRecoveryCodeUsed) {
+			return ErrorInvalidMFACode
+		}
+
+		return err
+	}
+
+	log.Warn("recovery code used")
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventRecoveryCodeUsed,
+		UserId: user.UniqueId,
+		AppID:  appID,
+		Details: map[string]string{
+			"remaining": strconv.Itoa(len(user.MFA.RecoveryCodes) - 1),
+		},
+	})
+
+	return nil
+}
+
+// generateRecoveryCodes returns new recovery codes and their digests.
+func (a *Auth) generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes, err := recoverycode.Generate(a.cfg.RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digests := make([][]byte, 0, len(codes))
+	for _, code := range codes {
+		digests = append(digests, recoverycode.Digest(a.cfg.EncryptionKey, code))
+	}
+
+	return codes, digests, nil
+}
+
+// saveAuditEvent records the event in the audit log.
+// A failing audit log does not fail the action, the failure is logged instead.
+func (a *Auth) saveAuditEvent(ctx context.Context, log *slog.Logger, event models.AuditEvent) {
+	event.EventId = uuid.New().String()
+	event.CreatedAt = time.Now()
+
+	if err := a.auditEventSaver.SaveAuditEvent(ctx, event); err != nil {
+		log.Error("failed to save audit event",
+			slog.String("type", event.Type),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// startMFAChallenge stores a challenge for a login which passed the password check
+// and returns the token the client completes it with.
+func (a *Auth) startMFAChallenge(ctx context.Context, user models.User, app models.App) (string, error) {
+	mfaToken, err := opaque.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := models.MFAChallenge{
+		UserId: user.UniqueId,
+		AppID:  app.AppID,
+	}
+
+	if err := a.mfaChallengeSaver.SaveMFAChallenge(ctx, opaque.Hash(mfaToken), challenge, a.cfg.MFAChallengeTTL); err != nil {
+		return "", err
+	}
+
+	return mfaToken, nil
+}
+
+// deleteMFAChallenge drops a challenge, the challenge expires on its own if that fails.
+func (a *Auth) deleteMFAChallenge(ctx context.Context, log *slog.Logger, tokenHash string) {
+	if err := a.mfaChallengeSaver.DeleteMFAChallenge(ctx, tokenHash); err != nil {
+		log.Error("failed to delete mfa challenge", slog.String("error", err.Error()))
+	}
+}
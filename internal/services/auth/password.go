@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/hasher"
+	"auth-sso/lib/mail"
+	"auth-sso/lib/opaque"
+	"auth-sso/lib/passwordpolicy"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// RequestPasswordReset sends a password reset link to the user with the given email.
+//
+// Unknown and disabled users are not reported, so the call can't be used to find out
+// which email addresses are registered.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) error {
+	const op = "auth.RequestPasswordReset"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("Requesting password reset")
+
+	user, err := a.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			log.Info("password reset requested for unknown user")
+
+			return nil
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", user.UniqueId))
+
+	if user.Disabled {
+		log.Warn("password reset requested for disabled user")
+
+		return nil
+	}
+
+	// Only the latest link sent to the user can be used.
+	if err := a.oneTimeTokenSaver.DeleteOneTimeTokens(ctx, user.UniqueId, models.OneTimeTokenPurposePasswordReset); err != nil {
+		log.Error("failed to delete previous reset tokens", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := a.createOneTimeToken(ctx, user.UniqueId, models.OneTimeTokenPurposePasswordReset, a.cfg.PasswordResetTTL)
+	if err != nil {
+		log.Error("failed to create reset token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.sendEmail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "A password reset was requested for your account.\n\n" +
+			"Use the following link to choose a new password, it expires in " + a.cfg.PasswordResetTTL.String() + ":\n\n" +
+			linkWithToken(a.cfg.PasswordResetURL, token) + "\n\n" +
+			"If you did not request a password reset, you can ignore this email.\n",
+	})
+	if err != nil {
+		log.Error("failed to dispatch password reset email", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset email dispatched")
+
+	return nil
+}
+
+// ResetPassword replaces the password of the user the reset token was issued for.
+// All sessions of the user are revoked.
+//
+// A password rejected by the password policy does not use up the token.
+func (a *Auth) ResetPassword(ctx context.Context, token string, password string) error {
+	const op = "auth.ResetPassword"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	tokenHash := opaque.Hash(token)
+
+	resetToken, err := a.oneTimeTokenProvider.OneTimeToken(ctx, tokenHash, models.OneTimeTokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, storage.ErrorOneTimeTokenNotFound) {
+			log.Warn("invalid password reset token")
+
+			return fmt.Errorf("%s: %w", op, ErrorInvalidResetToken)
+		}
+
+		log.Error("failed to get reset token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", resetToken.UserId))
+
+	user, err := a.userProvider.UserById(ctx, resetToken.UserId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorInvalidResetToken)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.validatePassword(password, user); err != nil {
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			log.Info("password rejected by the password policy")
+		} else {
+			log.Error("failed to validate password", slog.String("error", err.Error()))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.oneTimeTokenSaver.UseOneTimeToken(ctx, tokenHash, models.OneTimeTokenPurposePasswordReset); err != nil {
+		if errors.Is(err, storage.ErrorOneTimeTokenNotFound) {
+			log.Warn("password reset token used concurrently")
+
+			return fmt.Errorf("%s: %w", op, ErrorInvalidResetToken)
+		}
+
+		log.Error("failed to use reset token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.setPassword(ctx, user, password); err != nil {
+		log.Error("failed to update password", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.revokeSessions(ctx, user.UniqueId); err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventPasswordReset,
+		UserId: user.UniqueId,
+	})
+
+	log.Info("password reset")
+
+	return nil
+}
+
+// ChangePassword replaces the password of the user after checking the current one.
+//
+// All sessions of the user are revoked. The caller gets a new token pair for the app,
+// so only the other sessions are logged out.
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	userId string,
+	appID int,
+	currentPassword string,
+	newPassword string,
+) (models.TokenPair, error) {
+	const op = "auth.ChangePassword"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ok, err := hasher.Verify(user.PasswordHash, currentPassword); !ok {
+		if err != nil {
+			log.Error("failed to verify password hash", slog.String("error", err.Error()))
+		}
+
+		log.Info("invalid current password")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidCredentials)
+	}
+
+	if err := a.validatePassword(newPassword, user); err != nil {
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			log.Info("password rejected by the password policy")
+		} else {
+			log.Error("failed to validate password", slog.String("error", err.Error()))
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorAppNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorAppNotFound)
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.setPassword(ctx, user, newPassword); err != nil {
+		log.Error("failed to update password", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.revokeSessions(ctx, user.UniqueId); err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventPasswordChanged,
+		UserId: user.UniqueId,
+		AppID:  app.AppID,
+	})
+
+	tokens, err := a.issueTokens(ctx, user, app, uuid.New().String())
+	if err != nil {
+		log.Error("failed to generate tokens", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return tokens, nil
+}
+
+// validatePassword checks the new password of the user against the password policy,
+// including the user's previous passwords and, if enabled, the breached passwords corpus.
+// Returns a *passwordpolicy.Error listing the violations.
+func (a *Auth) validatePassword(password string, user models.User) error {
+	policy := a.cfg.PasswordPolicy
+
+	violations := policy.Validate(password, user.Email)
+
+	if policy.History > 0 && isPreviousPassword(password, user, policy.History) {
+		violations = append(violations, passwordpolicy.HistoryViolation(policy.History))
+	}
+
+	if a.breachChecker != nil {
+		count, err := a.breachChecker.Count(password)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 && count >= a.cfg.BreachMinCount {
+			violations = append(violations, passwordpolicy.BreachedViolation())
+		}
+	}
+
+	if len(violations) > 0 {
+		return &passwordpolicy.Error{Violations: violations}
+	}
+
+	return nil
+}
+
+// setPassword replaces the password of the user, keeping the current one in the password history.
+func (a *Auth) setPassword(ctx context.Context, user models.User, password string) error {
+	passwordHash, err := a.cfg.PasswordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	// The current password counts towards the history, so History-1 older hashes are kept.
+	var history [][]byte
+	if keep := a.cfg.PasswordPolicy.History - 1; keep > 0 {
+		history = append([][]byte{user.PasswordHash}, user.PasswordHistory...)
+		if len(history) > keep {
+			history = history[:keep]
+		}
+	}
+
+	return a.userSaver.UpdatePassword(ctx, user.UniqueId, passwordHash, history)
+}
+
+// isPreviousPassword reports whether the password matches the current or one of the last previous passwords.
+func isPreviousPassword(password string, user models.User, last int) bool {
+	hashes := append([][]byte{user.PasswordHash}, user.PasswordHistory...)
+	if len(hashes) > last {
+		hashes = hashes[:last]
+	}
+
+	for _, hash := range hashes {
+		if len(hash) == 0 {
+			continue
+		}
+
+		if ok, _ := hasher.Verify(hash, password); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rehashPassword replaces the password hash of the user if it was not created by the configured hasher.
+// The login does not depend on it, failures are only logged.
+func (a *Auth) rehashPassword(ctx context.Context, log *slog.Logger, user models.User, password string) {
+	if !a.cfg.PasswordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := a.cfg.PasswordHasher.Hash(password)
+	if err != nil {
+		log.Error("failed to rehash password", slog.String("error", err.Error()))
+
+		return
+	}
+
+	if err := a.userSaver.RehashPassword(ctx, user.UniqueId, user.PasswordHash, passwordHash); err != nil {
+		log.Error("failed to save rehashed password", slog.String("error", err.Error()))
+
+		return
+	}
+
+	log.Info("password rehashed", slog.String("userId", user.UniqueId))
+}
+
+// createOneTimeToken stores a new single use token of the purpose and returns it.
+func (a *Auth) createOneTimeToken(
+	ctx context.Context,
+	userId string,
+	purpose string,
+	ttl time.Duration,
+) (string, error) {
+	token, err := opaque.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	err = a.oneTimeTokenSaver.SaveOneTimeToken(ctx, models.OneTimeToken{
+		TokenId:   uuid.New().String(),
+		UserId:    userId,
+		Purpose:   purpose,
+		TokenHash: opaque.Hash(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// revokeSessions revokes all refresh tokens of the user and rejects the access tokens issued so far.
+func (a *Auth) revokeSessions(ctx context.Context, userId string) error {
+	if err := a.refreshTokenSaver.RevokeUserRefreshTokens(ctx, userId); err != nil {
+		return err
+	}
+
+	// Access tokens issued before now expire within the longest token TTL, so the marker is not needed any longer.
+	return a.tokenRevoker.RevokeUserTokens(ctx, userId, time.Now(), max(a.cfg.TokenTTL, a.cfg.MaxTokenTTL))
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/permission"
+	"auth-sso/lib/policy"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// applyPolicies narrows the granted checks by the policies which apply to them, policies never grant a permission.
+//
+// A deny policy whose condition holds rejects the check. If allow policies apply to the check,
+// the grant only stands when the condition of at least one of them holds. Deny overrides allow, like for grants.
+func (a *Auth) applyPolicies(
+	ctx context.Context,
+	log *slog.Logger,
+	checks []models.PermissionCheck,
+	granted []bool,
+	appID int,
+	attributes models.AuthorizationAttributes,
+) ([]bool, error) {
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		names = append(names, check.Permission)
+	}
+
+	policies, err := a.policyProvider.Policies(ctx, names, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policies) == 0 {
+		return granted, nil
+	}
+
+	resource := make(map[string]interface{}, len(attributes.Resource))
+	for key, value := range attributes.Resource {
+		resource[key] = value
+	}
+
+	environment := a.environmentAttributes(attributes.Environment)
+	subjects := make(map[string]map[string]interface{})
+
+	results := make([]bool, len(checks))
+	for i, check := range checks {
+		results[i] = granted[i]
+
+		// The resource and environment attributes come from the caller, they can only restrict a grant.
+		if !granted[i] {
+			continue
+		}
+
+		var applicable []models.Policy
+		for _, p := range policies {
+			if permission.Matches(p.Permission, check.Permission) {
+				applicable = append(applicable, p)
+			}
+		}
+
+		if len(applicable) == 0 {
+			continue
+		}
+
+		subject, ok := subjects[check.UserId]
+		if !ok {
+			subject, err = a.subjectAttributes(ctx, check.UserId, appID)
+			if err != nil {
+				return nil, err
+			}
+
+			subjects[check.UserId] = subject
+		}
+
+		input := policy.Input{
+			policy.RootSubject:     subject,
+			policy.RootResource:    resource,
+			policy.RootEnvironment: environment,
+		}
+
+		results[i] = a.evaluatePolicies(log, applicable, input)
+	}
+
+	return results, nil
+}
+
+// evaluatePolicies returns whether a granted check stands after the policies.
+// Policies which can't be evaluated fail closed: a broken deny policy counts as holding,
+// a broken allow policy as not holding.
+func (a *Auth) evaluatePolicies(log *slog.Logger, policies []models.Policy, input policy.Input) bool {
+	restricted, allowed := false, false
+
+	for _, p := range policies {
+		if p.Effect != models.PolicyEffectAllow && p.Effect != models.PolicyEffectDeny {
+			log.Error("policy has an unknown effect", slog.String("policyId", p.PolicyId), slog.String("effect", p.Effect))
+
+			return false
+		}
+
+		if p.Effect == models.PolicyEffectAllow {
+			restricted = true
+		}
+
+		holds, err := a.evaluateCondition(p.Condition, input)
+		if err != nil {
+			log.Error("failed to evaluate policy", slog.String("policyId", p.PolicyId), slog.String("error", err.Error()))
+
+			holds = p.Effect == models.PolicyEffectDeny
+		}
+
+		if !holds {
+			continue
+		}
+
+		if p.Effect == models.PolicyEffectDeny {
+			log.Info("denied by policy", slog.String("policyId", p.PolicyId))
+
+			return false
+		}
+
+		allowed = true
+	}
+
+	if restricted && !allowed {
+		log.Info("no allow policy holds")
+
+		return false
+	}
+
+	return true
+}
+
+func (a *Auth) evaluateCondition(condition string, input policy.Input) (bool, error) {
+	if cached, ok := a.conditions.Load(condition); ok {
+		return cached.(*policy.Expression).Evaluate(input)
+	}
+
+	expression, err := policy.Compile(condition)
+	if err != nil {
+		return false, err
+	}
+
+	a.conditions.Store(condition, expression)
+
+	return expression.Evaluate(input)
+}
+
+// subjectAttributes returns the attributes of the user for policies: the free-form attributes of the user,
+// and id, email, emailVerified and the roles the user has for the app, directly or through its groups,
+// which take precedence.
+// Unknown users only have an id.
+func (a *Auth) subjectAttributes(ctx context.Context, userId string, appID int) (map[string]interface{}, error) {
+	user, err := a.userProvider.UserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return map[string]interface{}{"id": userId}, nil
+		}
+
+		return nil, err
+	}
+
+	subject := make(map[string]interface{}, len(user.Attributes)+4)
+	for key, value := range user.Attributes {
+		subject[key] = value
+	}
+
+	authorization, err := a.permissionProvider.UserAuthorization(ctx, user.UniqueId, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := authorization.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
+	subject["id"] = user.UniqueId
+	subject["email"] = user.Email
+	subject["emailVerified"] = user.EmailVerified
+	subject["roles"] = roles
+
+	return subject, nil
+}
+
+// environmentAttributes returns the environment attributes passed by the caller along with the current
+// time, unix time, hour, minute, weekday and date in the policy time zone. The time attributes can't be overridden.
+func (a *Auth) environmentAttributes(passed map[string]string) map[string]interface{} {
+	environment := make(map[string]interface{}, len(passed)+5)
+	for key, value := range passed {
+		environment[key] = value
+	}
+
+	location := a.cfg.PolicyLocation
+	if location == nil {
+		location = time.UTC
+	}
+
+	now := time.Now().In(location)
+
+	environment["time"] = now.Format(time.RFC3339)
+	environment["hour"] = now.Hour()
+	environment["minute"] = now.Minute()
+	environment["weekday"] = now.Weekday().String()
+	environment["date"] = now.Format(time.DateOnly)
+	environment["unix"] = now.Unix()
+
+	return environment
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+const testAppID = 1
+
+type fakePermissions struct {
+	granted map[string]bool
+}
+
+func (f fakePermissions) Can(_ context.Context, permission string, userId string, _ int) (bool, error) {
+	return f.granted[userId+" "+permission], nil
+}
+
+func (f fakePermissions) CanBatch(ctx context.Context, checks []models.PermissionCheck, appID int) ([]bool, error) {
+	results := make([]bool, 0, len(checks))
+	for _, check := range checks {
+		can, _ := f.Can(ctx, check.Permission, check.UserId, appID)
+		results = append(results, can)
+	}
+
+	return results, nil
+}
+
+func (f fakePermissions) UserAuthorization(context.Context, string, int) (models.UserAuthorization, error) {
+	return models.UserAuthorization{}, nil
+}
+
+type fakePolicies []models.Policy
+
+func (f fakePolicies) Policies(context.Context, []string, int) ([]models.Policy, error) {
+	return f, nil
+}
+
+// unknownUsers knows no users, so policy subjects only have their id.
+type unknownUsers struct{}
+
+func (unknownUsers) User(context.Context, string) (models.User, error) {
+	return models.User{}, storage.ErrorUserNotFound
+}
+
+func (unknownUsers) UserById(context.Context, string) (models.User, error) {
+	return models.User{}, storage.ErrorUserNotFound
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestAuthorizePolicies(t *testing.T) {
+	const permission = "validations:read"
+
+	allowTenant := models.Policy{
+		PolicyId:   "tenant",
+		Permission: permission,
+		Effect:     models.PolicyEffectAllow,
+		Condition:  `resource.tenant == "acme"`,
+	}
+	denyNight := models.Policy{
+		PolicyId:   "night",
+		Permission: "validations:*",
+		Effect:     models.PolicyEffectDeny,
+		Condition:  `env.shift == "night"`,
+	}
+	broken := models.Policy{
+		PolicyId:   "broken",
+		Permission: permission,
+		Condition:  `resource.tenant ==`,
+	}
+
+	acme := models.AuthorizationAttributes{Resource: map[string]string{"tenant": "acme"}}
+	globex := models.AuthorizationAttributes{Resource: map[string]string{"tenant": "globex"}}
+	acmeAtNight := models.AuthorizationAttributes{
+		Resource:    map[string]string{"tenant": "acme"},
+		Environment: map[string]string{"shift": "night"},
+	}
+
+	tests := []struct {
+		name       string
+		granted    bool
+		policies   []models.Policy
+		attributes models.AuthorizationAttributes
+		want       bool
+	}{
+		{name: "grant without policies", granted: true, want: true},
+		{name: "no grant without policies", want: false},
+		{name: "policy allow without grant is denied", policies: []models.Policy{allowTenant}, attributes: acme, want: false},
+		{name: "allow policy holds", granted: true, policies: []models.Policy{allowTenant}, attributes: acme, want: true},
+		{name: "allow policy doesn't hold", granted: true, policies: []models.Policy{allowTenant}, attributes: globex, want: false},
+		{name: "deny policy holds", granted: true, policies: []models.Policy{denyNight}, attributes: acmeAtNight, want: false},
+		{name: "deny policy doesn't hold", granted: true, policies: []models.Policy{denyNight}, attributes: acme, want: true},
+		{name: "deny overrides allow", granted: true, policies: []models.Policy{allowTenant, denyNight}, attributes: acmeAtNight, want: false},
+		{
+			name:       "broken allow policy fails closed",
+			granted:    true,
+			policies:   []models.Policy{withEffect(broken, models.PolicyEffectAllow)},
+			attributes: acme,
+			want:       false,
+		},
+		{
+			name:       "broken deny policy fails closed",
+			granted:    true,
+			policies:   []models.Policy{withEffect(broken, models.PolicyEffectDeny)},
+			attributes: acme,
+			want:       false,
+		},
+		{
+			name:       "unknown effect fails closed",
+			granted:    true,
+			policies:   []models.Policy{withEffect(allowTenant, "audit")},
+			attributes: acme,
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Auth{
+				log:                testLogger(),
+				userProvider:       unknownUsers{},
+				permissionProvider: fakePermissions{granted: map[string]bool{"u1 " + permission: tt.granted}},
+				policyProvider:     fakePolicies(tt.policies),
+			}
+
+			got, err := a.Authorize(context.Background(), permission, "u1", testAppID, tt.attributes)
+			if err != nil {
+				t.Fatalf("Authorize() = %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchAuthorizePoliciesDontGrant(t *testing.T) {
+	a := &Auth{
+		log:                testLogger(),
+		userProvider:       unknownUsers{},
+		permissionProvider: fakePermissions{granted: map[string]bool{"u1 validations:read": true}},
+		policyProvider: fakePolicies{{
+			PolicyId:   "everyone",
+			Permission: "validations:*",
+			Effect:     models.PolicyEffectAllow,
+			Condition:  `true`,
+		}},
+	}
+
+	checks := []models.PermissionCheck{
+		{UserId: "u1", Permission: "validations:read"},
+		{UserId: "u1", Permission: "validations:write"},
+		{UserId: "u2", Permission: "validations:read"},
+	}
+
+	got, err := a.BatchAuthorize(context.Background(), checks, testAppID, models.AuthorizationAttributes{})
+	if err != nil {
+		t.Fatalf("BatchAuthorize() = %v", err)
+	}
+
+	want := []bool{true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("BatchAuthorize() = %v, want %v", got, want)
+		}
+	}
+}
+
+func withEffect(p models.Policy, effect string) models.Policy {
+	p.Effect = effect
+
+	return p
+}
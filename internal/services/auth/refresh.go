@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/jwt"
+	"auth-sso/lib/opaque"
+	"auth-sso/lib/permission"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+//
+// Refresh tokens are single use: the presented token is rotated on every call.
+// If an already used token is presented again, the whole token family is revoked,
+// since either the legitimate client or an attacker is holding a stolen copy.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	const op = "auth.Refresh"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("Refreshing tokens")
+
+	stored, err := a.refreshTokenProvider.RefreshToken(ctx, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrorRefreshTokenNotFound) {
+			log.Warn("refresh token not found")
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+		}
+
+		log.Error("failed to get refresh token", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("familyId", stored.FamilyId))
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		log.Warn("refresh token is revoked or expired")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+	}
+
+	if stored.UsedAt != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, a.handleRefreshTokenReuse(ctx, log, stored))
+	}
+
+	if err := a.refreshTokenSaver.UseRefreshToken(ctx, stored.TokenId); err != nil {
+		if errors.Is(err, storage.ErrorRefreshTokenUsed) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, a.handleRefreshTokenReuse(ctx, log, stored))
+		}
+
+		log.Error("failed to mark refresh token as used", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userProvider.UserById(ctx, stored.UserId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("user is disabled", slog.String("userId", user.UniqueId))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorInvalidRefreshToken)
+	}
+
+	app, err := a.appProvider.App(ctx, stored.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorAppNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrorAppNotFound)
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := a.issueTokens(ctx, user, app, stored.FamilyId)
+	if err != nil {
+		log.Error("failed to generate tokens", slog.String("error", err.Error()))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tokens refreshed")
+
+	return tokens, nil
+}
+
+func (a *Auth) handleRefreshTokenReuse(ctx context.Context, log *slog.Logger, token models.RefreshToken) error {
+	log.Warn("refresh token reuse detected, revoking token family", slog.String("userId", token.UserId))
+
+	if err := a.refreshTokenSaver.RevokeRefreshTokenFamily(ctx, token.FamilyId); err != nil {
+		log.Error("failed to revoke refresh token family", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	return ErrorInvalidRefreshToken
+}
+
+// issueTokens creates a new access token and a refresh token belonging to the given family.
+func (a *Auth) issueTokens(
+	ctx context.Context,
+	user models.User,
+	app models.App,
+	familyId string,
+) (models.TokenPair, error) {
+	key, err := a.keyProvider.SigningKey(ctx, app)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	authorization, err := a.tokenAuthorization(ctx, user, app)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	// Revoking all tokens of a user relies on knowing how long tokens live at most.
+	if maxSeconds := int(a.cfg.MaxTokenTTL / time.Second); app.TokenTTLSeconds > maxSeconds {
+		app.TokenTTLSeconds = maxSeconds
+	}
+
+	accessToken, err := jwt.NewToken(user, app, key, jwt.TokenOptions{
+		Issuer:   a.cfg.Issuer,
+		Audience: a.cfg.Audience,
+		TTL:      a.cfg.TokenTTL,
+	}, authorization)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	refreshToken, err := opaque.NewToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	now := time.Now()
+
+	err = a.refreshTokenSaver.SaveRefreshToken(ctx, models.RefreshToken{
+		TokenId:   uuid.New().String(),
+		FamilyId:  familyId,
+		UserId:    user.UniqueId,
+		AppID:     app.AppID,
+		TokenHash: opaque.Hash(refreshToken),
+		ExpiresAt: now.Add(a.cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// tokenAuthorization returns the roles and permissions to embed in the user's token for the app,
+// or nil if the app did not opt in.
+func (a *Auth) tokenAuthorization(ctx context.Context, user models.User, app models.App) (*jwt.Authorization, error) {
+	if app.AuthorizationClaims != models.AuthorizationClaimsFull && app.AuthorizationClaims != models.AuthorizationClaimsHash {
+		return nil, nil
+	}
+
+	userAuthorization, err := a.permissionProvider.UserAuthorization(ctx, user.UniqueId, app.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]permission.Grant, 0, len(userAuthorization.Permissions))
+	for _, grant := range userAuthorization.Permissions {
+		grants = append(grants, permission.Grant{Name: grant.Name, Deny: grant.Deny})
+	}
+
+	return &jwt.Authorization{
+		Roles:       userAuthorization.Roles,
+		Permissions: grants,
+		HashOnly:    app.AuthorizationClaims == models.AuthorizationClaimsHash,
+		MaxSize:     a.cfg.AuthorizationClaimsMaxSize,
+	}, nil
+}
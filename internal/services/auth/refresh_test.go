@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/jwt"
+	"auth-sso/lib/opaque"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeRefreshTokens stores refresh tokens in memory by their hash.
+type fakeRefreshTokens struct {
+	tokens map[string]*models.RefreshToken
+	// usedConcurrently makes UseRefreshToken fail as if another request used the token first.
+	usedConcurrently bool
+}
+
+func (f *fakeRefreshTokens) SaveRefreshToken(_ context.Context, token models.RefreshToken) error {
+	f.tokens[token.TokenHash] = &token
+
+	return nil
+}
+
+func (f *fakeRefreshTokens) UseRefreshToken(_ context.Context, tokenId string) error {
+	for _, token := range f.tokens {
+		if token.TokenId != tokenId {
+			continue
+		}
+
+		if token.UsedAt != nil || f.usedConcurrently {
+			return storage.ErrorRefreshTokenUsed
+		}
+
+		now := time.Now()
+		token.UsedAt = &now
+
+		return nil
+	}
+
+	return storage.ErrorRefreshTokenNotFound
+}
+
+func (f *fakeRefreshTokens) RevokeRefreshTokenFamily(_ context.Context, familyId string) error {
+	now := time.Now()
+
+	for _, token := range f.tokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeRefreshTokens) RevokeUserRefreshTokens(_ context.Context, userId string) error {
+	now := time.Now()
+
+	for _, token := range f.tokens {
+		if token.UserId == userId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeRefreshTokens) RefreshToken(_ context.Context, tokenHash string) (models.RefreshToken, error) {
+	token, ok := f.tokens[tokenHash]
+	if !ok {
+		return models.RefreshToken{}, storage.ErrorRefreshTokenNotFound
+	}
+
+	return *token, nil
+}
+
+// revoked reports whether every token of the family is revoked.
+func (f *fakeRefreshTokens) revoked(familyId string) bool {
+	for _, token := range f.tokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+type fakeUsers map[string]models.User
+
+func (f fakeUsers) User(_ context.Context, email string) (models.User, error) {
+	for _, user := range f {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrorUserNotFound
+}
+
+func (f fakeUsers) UserById(_ context.Context, id string) (models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return models.User{}, storage.ErrorUserNotFound
+	}
+
+	return user, nil
+}
+
+type fakeApps map[int]models.App
+
+func (f fakeApps) App(_ context.Context, appID int) (models.App, error) {
+	app, ok := f[appID]
+	if !ok {
+		return models.App{}, storage.ErrorAppNotFound
+	}
+
+	return app, nil
+}
+
+// fakeKeys signs every token with the secret of the app, the other methods aren't used by Refresh.
+type fakeKeys struct {
+	KeyProvider
+}
+
+func (fakeKeys) SigningKey(_ context.Context, app models.App) (jwt.SigningKey, error) {
+	return jwt.SigningKey{Algorithm: jwt.AlgorithmHS512, PrivateKey: []byte(app.Secret)}, nil
+}
+
+const testFamilyId = "family"
+
+var (
+	testRefreshUser = models.User{UniqueId: "u1", Email: "alice@example.com"}
+	testRefreshApp  = models.App{AppID: testAppID, Secret: "secret"}
+)
+
+// newRefreshAuth returns an Auth with in-memory storages and the refresh token it issued for testRefreshUser.
+func newRefreshAuth(t *testing.T, user models.User) (*Auth, *fakeRefreshTokens, string) {
+	t.Helper()
+
+	refreshTokens := &fakeRefreshTokens{tokens: map[string]*models.RefreshToken{}}
+
+	a := &Auth{
+		log:                  testLogger(),
+		userProvider:         fakeUsers{user.UniqueId: user},
+		appProvider:          fakeApps{testRefreshApp.AppID: testRefreshApp},
+		permissionProvider:   fakePermissions{},
+		refreshTokenSaver:    refreshTokens,
+		refreshTokenProvider: refreshTokens,
+		keyProvider:          fakeKeys{},
+		cfg: Config{
+			TokenTTL:        time.Minute,
+			MaxTokenTTL:     time.Hour,
+			RefreshTokenTTL: time.Hour,
+			Issuer:          "https://sso.example.com",
+			Audience:        []string{"auth-sso"},
+		},
+	}
+
+	tokens, err := a.issueTokens(context.Background(), user, testRefreshApp, testFamilyId)
+	if err != nil {
+		t.Fatalf("issueTokens() = %v", err)
+	}
+
+	return a, refreshTokens, tokens.RefreshToken
+}
+
+func TestRefreshRotates(t *testing.T) {
+	a, refreshTokens, first := newRefreshAuth(t, testRefreshUser)
+
+	second, err := a.Refresh(context.Background(), first)
+	if err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+
+	if second.AccessToken == "" || second.RefreshToken == "" || second.RefreshToken == first {
+		t.Fatalf("Refresh() = %+v, want a new token pair", second)
+	}
+
+	rotated := refreshTokens.tokens[opaque.Hash(second.RefreshToken)]
+	if rotated == nil || rotated.FamilyId != testFamilyId {
+		t.Fatalf("rotated token = %+v, want it in family %q", rotated, testFamilyId)
+	}
+
+	if refreshTokens.tokens[opaque.Hash(first)].UsedAt == nil {
+		t.Fatal("presented token wasn't marked as used")
+	}
+
+	if _, err := a.Refresh(context.Background(), second.RefreshToken); err != nil {
+		t.Fatalf("Refresh() with the rotated token = %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	tests := []struct {
+		name string
+		// reuse presents the token after it was used and returns the newest token of the family.
+		reuse func(t *testing.T, a *Auth, refreshTokens *fakeRefreshTokens, token string) (string, error)
+	}{
+		{
+			name: "used token presented again",
+			reuse: func(t *testing.T, a *Auth, _ *fakeRefreshTokens, token string) (string, error) {
+				rotated, err := a.Refresh(context.Background(), token)
+				if err != nil {
+					t.Fatalf("first Refresh() = %v", err)
+				}
+
+				_, err = a.Refresh(context.Background(), token)
+
+				return rotated.RefreshToken, err
+			},
+		},
+		{
+			name: "token used concurrently",
+			reuse: func(_ *testing.T, a *Auth, refreshTokens *fakeRefreshTokens, token string) (string, error) {
+				refreshTokens.usedConcurrently = true
+				defer func() { refreshTokens.usedConcurrently = false }()
+
+				_, err := a.Refresh(context.Background(), token)
+
+				return token, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, refreshTokens, token := newRefreshAuth(t, testRefreshUser)
+
+			newest, err := tt.reuse(t, a, refreshTokens, token)
+			if !errors.Is(err, ErrorInvalidRefreshToken) {
+				t.Fatalf("Refresh() with a used token = %v, want ErrorInvalidRefreshToken", err)
+			}
+
+			if !refreshTokens.revoked(testFamilyId) {
+				t.Fatal("token family wasn't revoked")
+			}
+
+			// The newest token of the family is revoked too, whoever holds it.
+			if _, err := a.Refresh(context.Background(), newest); !errors.Is(err, ErrorInvalidRefreshToken) {
+				t.Fatalf("Refresh() with the newest token = %v, want ErrorInvalidRefreshToken", err)
+			}
+		})
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name   string
+		user   models.User
+		change func(token *models.RefreshToken)
+		token  string
+	}{
+		{name: "unknown token", user: testRefreshUser, token: "unknown"},
+		{name: "expired", user: testRefreshUser, change: func(token *models.RefreshToken) { token.ExpiresAt = past }},
+		{name: "revoked", user: testRefreshUser, change: func(token *models.RefreshToken) { token.RevokedAt = &past }},
+		{name: "disabled user", user: models.User{UniqueId: "u1", Email: "alice@example.com", Disabled: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, refreshTokens, token := newRefreshAuth(t, tt.user)
+
+			if tt.change != nil {
+				tt.change(refreshTokens.tokens[opaque.Hash(token)])
+			}
+
+			if tt.token != "" {
+				token = tt.token
+			}
+
+			if _, err := a.Refresh(context.Background(), token); !errors.Is(err, ErrorInvalidRefreshToken) {
+				t.Fatalf("Refresh() = %v, want ErrorInvalidRefreshToken", err)
+			}
+		})
+	}
+}
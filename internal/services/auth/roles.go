@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/permission"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CreateRole creates a new role bundling the given permissions for the app,
+// or for all apps if appID is models.AppIDAll.
+func (a *Auth) CreateRole(ctx context.Context, name string, permissions []string, appID int) (models.Role, error) {
+	const op = "auth.CreateRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("role", name),
+	)
+
+	if err := a.checkAppScope(ctx, appID); err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	role := models.Role{
+		Name:        name,
+		Permissions: make([]models.Permission, 0, len(permissions)),
+		CreatedAt:   time.Now(),
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	for _, name := range permissions {
+		if err := permission.Validate(name); err != nil {
+			return models.Role{}, fmt.Errorf("%s: %w", op, ErrorInvalidPermission)
+		}
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		role.Permissions = append(role.Permissions, models.Permission{Name: name, AppID: appID})
+	}
+
+	if err := a.roleSaver.SaveRole(ctx, role); err != nil {
+		if errors.Is(err, storage.ErrorRoleExists) {
+			return models.Role{}, fmt.Errorf("%s: %w", op, ErrorRoleExists)
+		}
+
+		log.Error("failed to save role", slog.String("error", err.Error()))
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role created")
+
+	return role, nil
+}
+
+// GrantRolePermission adds the permission for the app to the role, granting it to every user assigned to the role.
+// With deny set the permission is denied instead, overriding the grants of other roles.
+func (a *Auth) GrantRolePermission(
+	ctx context.Context,
+	roleName string,
+	name string,
+	appID int,
+	deny bool,
+) error {
+	const op = "auth.GrantRolePermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("role", roleName),
+		slog.String("permission", name),
+		slog.Int("appId", appID),
+		slog.Bool("deny", deny),
+	)
+
+	if err := permission.Validate(name); err != nil {
+		return fmt.Errorf("%s: %w", op, ErrorInvalidPermission)
+	}
+
+	if err := a.checkAppScope(ctx, appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := a.roleSaver.GrantRolePermission(ctx, roleName, models.Permission{Name: name, AppID: appID, Deny: deny})
+	if err != nil {
+		if errors.Is(err, storage.ErrorRoleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorRoleNotFound)
+		}
+
+		log.Error("failed to grant permission", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("permission granted")
+
+	return nil
+}
+
+// RevokeRolePermission removes the allow or deny grant of the permission for the app from the role.
+// Users keep the permission if they have it directly or through another role.
+func (a *Auth) RevokeRolePermission(ctx context.Context, roleName string, name string, appID int) error {
+	const op = "auth.RevokeRolePermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("role", roleName),
+		slog.String("permission", name),
+		slog.Int("appId", appID),
+	)
+
+	err := a.roleSaver.RevokeRolePermission(ctx, roleName, models.Permission{Name: name, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorRoleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorRoleNotFound)
+		}
+
+		log.Error("failed to revoke permission", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("permission revoked")
+
+	return nil
+}
+
+// AssignRole assigns the role to the user for the app, or for all apps if appID is models.AppIDAll.
+func (a *Auth) AssignRole(ctx context.Context, userId string, roleName string, appID int) error {
+	const op = "auth.AssignRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+		slog.String("role", roleName),
+		slog.Int("appId", appID),
+	)
+
+	if err := a.checkAppScope(ctx, appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.roleProvider.Role(ctx, roleName); err != nil {
+		if errors.Is(err, storage.ErrorRoleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorRoleNotFound)
+		}
+
+		log.Error("failed to get role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := a.roleSaver.AssignRole(ctx, userId, models.RoleAssignment{Role: roleName, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to assign role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventRoleAssigned,
+		UserId:  userId,
+		AppID:   appID,
+		Details: map[string]string{"role": roleName},
+	})
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+// UnassignRole removes the assignment of the role for the app from the user.
+func (a *Auth) UnassignRole(ctx context.Context, userId string, roleName string, appID int) error {
+	const op = "auth.UnassignRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+		slog.String("role", roleName),
+		slog.Int("appId", appID),
+	)
+
+	err := a.roleSaver.UnassignRole(ctx, userId, models.RoleAssignment{Role: roleName, AppID: appID})
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorUserNotFound)
+		}
+
+		log.Error("failed to unassign role", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:    models.AuditEventRoleUnassigned,
+		UserId:  userId,
+		AppID:   appID,
+		Details: map[string]string{"role": roleName},
+	})
+
+	log.Info("role unassigned")
+
+	return nil
+}
+
+// checkAppScope returns ErrorAppNotFound unless appID is models.AppIDAll or an existing app.
+func (a *Auth) checkAppScope(ctx context.Context, appID int) error {
+	if appID == models.AppIDAll {
+		return nil
+	}
+
+	if _, err := a.appProvider.App(ctx, appID); err != nil {
+		if errors.Is(err, storage.ErrorAppNotFound) {
+			return ErrorAppNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
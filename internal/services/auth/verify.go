@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"auth-sso/lib/jwt"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Verify validates an access token issued by the service and returns its claims.
+// Tokens revoked by Logout, and tokens issued before the user's sessions were revoked
+// (e.g. by a password reset), are rejected even though they did not expire yet.
+func (a *Auth) Verify(ctx context.Context, token string) (jwt.Claims, error) {
+	const op = "auth.Verify"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := a.parseToken(ctx, token)
+	if err != nil {
+		log.Debug("invalid access token", slog.String("error", err.Error()))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrorInvalidToken)
+	}
+
+	revoked, err := a.revokedTokenProvider.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		log.Error("failed to check token revocation", slog.String("error", err.Error()))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revoked {
+		log.Debug("access token is revoked", slog.String("tokenId", claims.ID))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrorInvalidToken)
+	}
+
+	revokedBefore, err := a.revokedTokenProvider.UserTokensRevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		log.Error("failed to check user token revocation", slog.String("error", err.Error()))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !revokedBefore.IsZero() && claims.IssuedAt.Before(revokedBefore) {
+		log.Debug("access token was issued before the user's tokens were revoked", slog.String("tokenId", claims.ID))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrorInvalidToken)
+	}
+
+	return claims, nil
+}
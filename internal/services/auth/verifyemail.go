@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/mail"
+	"auth-sso/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// VerifyEmail marks the email address of the user the verification token was sent to as verified.
+func (a *Auth) VerifyEmail(ctx context.Context, token string) error {
+	const op = "auth.VerifyEmail"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	verifyToken, err := a.oneTimeTokenSaver.UseOneTimeToken(ctx, opaque.Hash(token), models.OneTimeTokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, storage.ErrorOneTimeTokenNotFound) {
+			log.Warn("invalid email verification token")
+
+			return fmt.Errorf("%s: %w", op, ErrorInvalidVerifyToken)
+		}
+
+		log.Error("failed to use verification token", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", verifyToken.UserId))
+
+	if err := a.userSaver.MarkEmailVerified(ctx, verifyToken.UserId); err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorInvalidVerifyToken)
+		}
+
+		log.Error("failed to mark email as verified", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.saveAuditEvent(ctx, log, models.AuditEvent{
+		Type:   models.AuditEventEmailVerified,
+		UserId: verifyToken.UserId,
+	})
+
+	log.Info("email verified")
+
+	return nil
+}
+
+// ResendVerificationEmail sends a new link verifying the email address to the user with the given email.
+// Links sent before can no longer be used.
+//
+// Unknown, disabled and already verified users are not reported, so the call can't be used to find out
+// which email addresses are registered. Requests beyond the limit per address return ErrorTooManyRequests,
+// whether the address is registered or not.
+func (a *Auth) ResendVerificationEmail(ctx context.Context, email string) error {
+	const op = "auth.ResendVerificationEmail"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("Resending verification email")
+
+	requests, err := a.requestLimiter.CountRequest(ctx, verificationEmailSubject(email), a.cfg.VerificationResendWindow)
+	if err != nil {
+		log.Error("failed to count verification email request", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if requests > int64(a.cfg.VerificationResendLimit) {
+		log.Warn("too many verification email requests")
+
+		return fmt.Errorf("%s: %w", op, ErrorTooManyRequests)
+	}
+
+	user, err := a.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrorUserNotFound) {
+			log.Info("verification email requested for unknown user")
+
+			return nil
+		}
+
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("userId", user.UniqueId))
+
+	if user.Disabled || user.EmailVerified {
+		log.Info("verification email requested for disabled or verified user")
+
+		return nil
+	}
+
+	if err := a.sendVerificationEmail(ctx, user.UniqueId, user.Email); err != nil {
+		log.Error("failed to dispatch verification email", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("verification email dispatched")
+
+	return nil
+}
+
+// sendVerificationEmail sends a link verifying the email address to the user.
+func (a *Auth) sendVerificationEmail(ctx context.Context, userId string, email string) error {
+	if err := a.oneTimeTokenSaver.DeleteOneTimeTokens(ctx, userId, models.OneTimeTokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := a.createOneTimeToken(ctx, userId, models.OneTimeTokenPurposeEmailVerification, a.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return a.sendEmail(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: "Please verify your email address with the following link, it expires in " +
+			a.cfg.EmailVerificationTTL.String() + ":\n\n" +
+			linkWithToken(a.cfg.EmailVerificationURL, token) + "\n\n" +
+			"If you did not create an account, you can ignore this email.\n",
+	})
+}
+
+func verificationEmailSubject(email string) string {
+	return "verification-email:" + strings.ToLower(email)
+}
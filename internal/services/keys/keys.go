@@ -0,0 +1,431 @@
+package keys
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"auth-sso/lib/encryption"
+	"auth-sso/lib/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// globalAppID is the app ID of keys shared by all apps without their own signing algorithm.
+const globalAppID = 0
+
+type Keys struct {
+	log            *slog.Logger
+	keySaver       KeySaver
+	keyProvider    KeyProvider
+	algorithm      string
+	encryptionKey  []byte
+	rotationPeriod time.Duration
+	overlap        time.Duration
+
+	mu     sync.RWMutex
+	parsed map[string]jwt.SigningKey
+}
+
+type KeySaver interface {
+	SaveSigningKey(ctx context.Context, key models.SigningKey) error
+	MarkSigningKeysRotated(ctx context.Context,
+		appID int,
+		algorithm string,
+		activeKeyId string,
+		rotatedAt time.Time,
+	) error
+	RetireSigningKey(ctx context.Context, keyId string, retiredAt time.Time) error
+}
+
+type KeyProvider interface {
+	ActiveSigningKey(ctx context.Context, appID int, algorithm string) (models.SigningKey, error)
+	SigningKeyById(ctx context.Context, keyId string) (models.SigningKey, error)
+	SigningKeys(ctx context.Context) ([]models.SigningKey, error)
+}
+
+var (
+	ErrorKeyNotFound       = errors.New("signing key not found")
+	ErrorSymmetricKey      = errors.New("tokens are signed with the app secret")
+	ErrorActiveKeyRetiring = errors.New("active signing key can not be retired, rotate it first")
+)
+
+// New returns a new instance of the Keys service.
+//
+// The algorithm is used for all apps which don't define their own signing algorithm.
+// Active keys are replaced after the rotation period, replaced keys keep verifying
+// tokens for the overlap window and are retired afterwards.
+func New(
+	log *slog.Logger,
+	keySaver KeySaver,
+	keyProvider KeyProvider,
+	algorithm string,
+	encryptionKey []byte,
+	rotationPeriod time.Duration,
+	overlap time.Duration,
+) *Keys {
+	return &Keys{
+		log:            log,
+		keySaver:       keySaver,
+		keyProvider:    keyProvider,
+		algorithm:      algorithm,
+		encryptionKey:  encryptionKey,
+		rotationPeriod: rotationPeriod,
+		overlap:        overlap,
+		parsed:         make(map[string]jwt.SigningKey),
+	}
+}
+
+// SigningKey returns the key tokens of the given app are signed with.
+//
+// Apps using HS512 sign with their own secret. For asymmetric algorithms
+// a key pair is generated on first use, per app if the app overrides the
+// algorithm and shared between apps otherwise.
+func (k *Keys) SigningKey(ctx context.Context, app models.App) (jwt.SigningKey, error) {
+	const op = "keys.SigningKey"
+
+	appID, algorithm := k.scope(app)
+
+	if algorithm == jwt.AlgorithmHS512 {
+		return jwt.SigningKey{
+			Algorithm:  jwt.AlgorithmHS512,
+			PrivateKey: []byte(app.Secret),
+		}, nil
+	}
+
+	if !jwt.IsAsymmetric(algorithm) {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w: %s", op, jwt.ErrorUnsupportedAlgorithm, algorithm)
+	}
+
+	stored, err := k.keyProvider.ActiveSigningKey(ctx, appID, algorithm)
+	if errors.Is(err, storage.ErrorSigningKeyNotFound) {
+		stored, err = k.create(ctx, appID, algorithm)
+	}
+
+	if err != nil {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := k.decode(stored)
+	if err != nil {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// VerificationKey returns the key with the given ID if tokens of the app may be verified with it.
+// Retired keys are never returned.
+func (k *Keys) VerificationKey(ctx context.Context, keyId string, appID int) (jwt.SigningKey, error) {
+	const op = "keys.VerificationKey"
+
+	stored, err := k.keyProvider.SigningKeyById(ctx, keyId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorSigningKeyNotFound) {
+			return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, ErrorKeyNotFound)
+		}
+
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stored.Status == models.SigningKeyStatusRetired {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, ErrorKeyNotFound)
+	}
+
+	if stored.AppID != globalAppID && stored.AppID != appID {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, ErrorKeyNotFound)
+	}
+
+	key, err := k.decode(stored)
+	if err != nil {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// PublicKeys returns the public parts of all keys tokens may currently be verified with.
+func (k *Keys) PublicKeys(ctx context.Context) ([]jwt.JWK, error) {
+	const op = "keys.PublicKeys"
+
+	stored, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	jwks := make([]jwt.JWK, 0, len(stored))
+
+	for _, s := range stored {
+		if s.Status == models.SigningKeyStatusRetired {
+			continue
+		}
+
+		key, err := k.decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	return jwks, nil
+}
+
+// Keys lists all signing keys, including rotated and retired ones.
+// The private key material is not part of the result.
+func (k *Keys) Keys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "keys.Keys"
+
+	stored, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range stored {
+		stored[i].PrivateKey = nil
+		if stored[i].Status == "" {
+			stored[i].Status = models.SigningKeyStatusActive
+		}
+	}
+
+	return stored, nil
+}
+
+// Rotate replaces the active key of the app with a newly generated one.
+// The replaced key stays valid for verification until the overlap window ends.
+func (k *Keys) Rotate(ctx context.Context, app models.App) (models.SigningKey, error) {
+	const op = "keys.Rotate"
+
+	appID, algorithm := k.scope(app)
+
+	if !jwt.IsAsymmetric(algorithm) {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, ErrorSymmetricKey)
+	}
+
+	key, err := k.rotate(ctx, appID, algorithm)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key.PrivateKey = nil
+
+	return key, nil
+}
+
+// Retire immediately stops the key from being used for verification.
+func (k *Keys) Retire(ctx context.Context, keyId string) error {
+	const op = "keys.Retire"
+
+	log := k.log.With(
+		slog.String("op", op),
+		slog.String("keyId", keyId),
+	)
+
+	stored, err := k.keyProvider.SigningKeyById(ctx, keyId)
+	if err != nil {
+		if errors.Is(err, storage.ErrorSigningKeyNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrorKeyNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stored.Status == "" || stored.Status == models.SigningKeyStatusActive {
+		return fmt.Errorf("%s: %w", op, ErrorActiveKeyRetiring)
+	}
+
+	if err := k.keySaver.RetireSigningKey(ctx, keyId, time.Now()); err != nil {
+		log.Error("failed to retire signing key", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Signing key retired")
+
+	return nil
+}
+
+// RotateExpired rotates active keys older than the rotation period and
+// retires rotated keys whose overlap window has ended.
+func (k *Keys) RotateExpired(ctx context.Context) error {
+	const op = "keys.RotateExpired"
+
+	log := k.log.With(
+		slog.String("op", op),
+	)
+
+	stored, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	rotated := make(map[string]bool)
+
+	for _, key := range stored {
+		var err error
+
+		switch key.Status {
+		case "", models.SigningKeyStatusActive:
+			scope := fmt.Sprintf("%d/%s", key.AppID, key.Algorithm)
+			if rotated[scope] {
+				continue
+			}
+
+			// Shared keys of an algorithm that is no longer configured are phased out instead of renewed.
+			if key.AppID == globalAppID && key.Algorithm != k.algorithm {
+				err = k.keySaver.MarkSigningKeysRotated(ctx, key.AppID, key.Algorithm, "", now)
+				rotated[scope] = true
+			} else if now.Sub(key.CreatedAt) >= k.rotationPeriod {
+				_, err = k.rotate(ctx, key.AppID, key.Algorithm)
+				rotated[scope] = true
+			}
+		case models.SigningKeyStatusRotated:
+			if key.RotatedAt != nil && now.Sub(*key.RotatedAt) >= k.overlap {
+				err = k.keySaver.RetireSigningKey(ctx, key.KeyId, now)
+				if err == nil {
+					log.Info("Signing key retired", slog.String("keyId", key.KeyId))
+				}
+			}
+		}
+
+		if err != nil {
+			log.Error("failed to rotate signing key",
+				slog.String("keyId", key.KeyId),
+				slog.String("error", err.Error()),
+			)
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// scope returns the app ID and algorithm the keys of the app are stored under.
+func (k *Keys) scope(app models.App) (int, string) {
+	if app.SigningAlgorithm != "" {
+		return app.AppID, app.SigningAlgorithm
+	}
+
+	return globalAppID, k.algorithm
+}
+
+// rotate moves the active keys into the overlap window and generates a new active key.
+func (k *Keys) rotate(ctx context.Context, appID int, algorithm string) (models.SigningKey, error) {
+	const op = "keys.rotate"
+
+	if err := k.keySaver.MarkSigningKeysRotated(ctx, appID, algorithm, "", time.Now()); err != nil {
+		k.log.Error("failed to mark signing keys as rotated",
+			slog.String("op", op),
+			slog.Int("appId", appID),
+			slog.String("algorithm", algorithm),
+			slog.String("error", err.Error()),
+		)
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := k.create(ctx, appID, algorithm)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// create generates a new active key unless the app already has one, which can happen if keys are
+// created concurrently. Either way the active key is returned, so there is only ever one.
+func (k *Keys) create(ctx context.Context, appID int, algorithm string) (models.SigningKey, error) {
+	const op = "keys.create"
+
+	log := k.log.With(
+		slog.String("op", op),
+		slog.Int("appId", appID),
+		slog.String("algorithm", algorithm),
+	)
+
+	log.Info("Generating signing key")
+
+	key, err := jwt.GenerateKey(uuid.New().String(), algorithm)
+	if err != nil {
+		log.Error("failed to generate signing key", slog.String("error", err.Error()))
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	encoded, err := jwt.EncodePrivateKey(key)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	encrypted, err := encryption.Encrypt(k.encryptionKey, encoded)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored := models.SigningKey{
+		KeyId:      key.ID,
+		AppID:      appID,
+		Algorithm:  algorithm,
+		Status:     models.SigningKeyStatusActive,
+		PrivateKey: encrypted,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := k.keySaver.SaveSigningKey(ctx, stored); err != nil {
+		if !errors.Is(err, storage.ErrorSigningKeyExists) {
+			log.Error("failed to save signing key", slog.String("error", err.Error()))
+
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("Another signing key was activated meanwhile, using it")
+
+		active, err := k.keyProvider.ActiveSigningKey(ctx, appID, algorithm)
+		if err != nil {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return active, nil
+	}
+
+	log.Info("Signing key generated", slog.String("keyId", key.ID))
+
+	return stored, nil
+}
+
+// decode decrypts and parses a stored key, caching the result since key material never changes.
+func (k *Keys) decode(stored models.SigningKey) (jwt.SigningKey, error) {
+	k.mu.RLock()
+	key, ok := k.parsed[stored.KeyId]
+	k.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	encoded, err := encryption.Decrypt(k.encryptionKey, stored.PrivateKey)
+	if err != nil {
+		return jwt.SigningKey{}, err
+	}
+
+	key, err = jwt.DecodePrivateKey(stored.KeyId, stored.Algorithm, encoded)
+	if err != nil {
+		return jwt.SigningKey{}, err
+	}
+
+	k.mu.Lock()
+	k.parsed[stored.KeyId] = key
+	k.mu.Unlock()
+
+	return key, nil
+}
@@ -0,0 +1,332 @@
+package relations
+
+import (
+	"auth-sso/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Relations answers relationship-based authorization questions from relation tuples.
+//
+// The namespaces define the relations objects can have. A relation may include other relations
+// of the same object (computed usersets), e.g. editors of a document are also its viewers.
+// Tuples may point to subject sets, e.g. members of a group, which are resolved recursively.
+type Relations struct {
+	log           *slog.Logger
+	tupleSaver    TupleSaver
+	tupleProvider TupleProvider
+	namespaces    map[string]map[string]Relation
+	maxDepth      int
+}
+
+// Namespace is a type of object, e.g. document, and the relations its objects can have.
+type Namespace struct {
+	Name      string
+	Relations []Relation
+}
+
+type Relation struct {
+	Name string
+	// Includes are relations of the same object whose subjects also have this relation.
+	Includes []string
+}
+
+type TupleSaver interface {
+	WriteRelationTuples(ctx context.Context, writes []models.RelationTuple, deletes []models.RelationTuple) error
+}
+
+type TupleProvider interface {
+	RelationTuples(ctx context.Context,
+		namespace string,
+		objectId string,
+		relations []string,
+		userId string,
+	) ([]models.RelationTuple, error)
+}
+
+var (
+	ErrorUnknownNamespace = errors.New("unknown namespace")
+	ErrorUnknownRelation  = errors.New("unknown relation")
+	ErrorInvalidTuple     = errors.New("invalid relation tuple")
+	ErrorDepthExceeded    = errors.New("maximum relation depth exceeded")
+)
+
+// New returns a new instance of the Relations service.
+//
+// maxDepth limits how many subject sets are followed from the checked object.
+// Returns an error if a relation includes a relation its namespace doesn't define.
+func New(
+	log *slog.Logger,
+	tupleSaver TupleSaver,
+	tupleProvider TupleProvider,
+	namespaces []Namespace,
+	maxDepth int,
+) (*Relations, error) {
+	const op = "relations.New"
+
+	r := &Relations{
+		log:           log,
+		tupleSaver:    tupleSaver,
+		tupleProvider: tupleProvider,
+		namespaces:    make(map[string]map[string]Relation, len(namespaces)),
+		maxDepth:      maxDepth,
+	}
+
+	for _, namespace := range namespaces {
+		relations := make(map[string]Relation, len(namespace.Relations))
+		for _, relation := range namespace.Relations {
+			relations[relation.Name] = relation
+		}
+
+		for _, relation := range namespace.Relations {
+			for _, include := range relation.Includes {
+				if _, ok := relations[include]; !ok {
+					return nil, fmt.Errorf("%s: %w: %s#%s includes %s", op, ErrorUnknownRelation, namespace.Name, relation.Name, include)
+				}
+			}
+		}
+
+		r.namespaces[namespace.Name] = relations
+	}
+
+	return r, nil
+}
+
+// Check reports whether the user has the relation to the object,
+// directly, through an included relation or through a subject set.
+func (r *Relations) Check(ctx context.Context, namespace string, objectId string, relation string, userId string) (bool, error) {
+	const op = "relations.Check"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("object", namespace+":"+objectId+"#"+relation),
+	)
+
+	if err := r.validateRelation(namespace, relation); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	allowed, err := r.check(ctx, namespace, objectId, relation, userId, 0, make(map[string]bool))
+	if err != nil {
+		if !errors.Is(err, ErrorDepthExceeded) {
+			log.Error("failed to check relation", slog.String("error", err.Error()))
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return allowed, nil
+}
+
+func (r *Relations) check(
+	ctx context.Context,
+	namespace string,
+	objectId string,
+	relation string,
+	userId string,
+	depth int,
+	visited map[string]bool,
+) (bool, error) {
+	if depth > r.maxDepth {
+		return false, ErrorDepthExceeded
+	}
+
+	// Subject sets may form cycles, an object relation already being checked can't add anything.
+	key := namespace + ":" + objectId + "#" + relation
+	if visited[key] {
+		return false, nil
+	}
+
+	visited[key] = true
+
+	tuples, err := r.tupleProvider.RelationTuples(ctx, namespace, objectId, r.includedRelations(namespace, relation), userId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, tuple := range tuples {
+		if tuple.Subject.UserId == userId {
+			return true, nil
+		}
+	}
+
+	for _, tuple := range tuples {
+		if tuple.Subject.IsUser() {
+			continue
+		}
+
+		ok, err := r.check(ctx, tuple.Subject.Namespace, tuple.Subject.ObjectId, tuple.Subject.Relation, userId, depth+1, visited)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// Expand returns the tree of subjects having the relation to the object.
+// Subject sets which were already expanded elsewhere in the tree are not expanded again.
+func (r *Relations) Expand(ctx context.Context, namespace string, objectId string, relation string) (models.UsersetTree, error) {
+	const op = "relations.Expand"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("object", namespace+":"+objectId+"#"+relation),
+	)
+
+	if err := r.validateRelation(namespace, relation); err != nil {
+		return models.UsersetTree{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tree, err := r.expand(ctx, namespace, objectId, relation, 0, make(map[string]bool))
+	if err != nil {
+		if !errors.Is(err, ErrorDepthExceeded) {
+			log.Error("failed to expand relation", slog.String("error", err.Error()))
+		}
+
+		return models.UsersetTree{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tree, nil
+}
+
+func (r *Relations) expand(
+	ctx context.Context,
+	namespace string,
+	objectId string,
+	relation string,
+	depth int,
+	expanded map[string]bool,
+) (models.UsersetTree, error) {
+	tree := models.UsersetTree{
+		Namespace: namespace,
+		ObjectId:  objectId,
+		Relation:  relation,
+	}
+
+	if depth > r.maxDepth {
+		return models.UsersetTree{}, ErrorDepthExceeded
+	}
+
+	key := namespace + ":" + objectId + "#" + relation
+	if expanded[key] {
+		return tree, nil
+	}
+
+	expanded[key] = true
+
+	tuples, err := r.tupleProvider.RelationTuples(ctx, namespace, objectId, []string{relation}, "")
+	if err != nil {
+		return models.UsersetTree{}, err
+	}
+
+	for _, tuple := range tuples {
+		if tuple.Subject.IsUser() {
+			tree.Users = append(tree.Users, tuple.Subject.UserId)
+
+			continue
+		}
+
+		child, err := r.expand(ctx, tuple.Subject.Namespace, tuple.Subject.ObjectId, tuple.Subject.Relation, depth+1, expanded)
+		if err != nil {
+			return models.UsersetTree{}, err
+		}
+
+		tree.Children = append(tree.Children, child)
+	}
+
+	for _, include := range r.namespaces[namespace][relation].Includes {
+		child, err := r.expand(ctx, namespace, objectId, include, depth+1, expanded)
+		if err != nil {
+			return models.UsersetTree{}, err
+		}
+
+		tree.Children = append(tree.Children, child)
+	}
+
+	return tree, nil
+}
+
+// WriteTuples deletes and writes relation tuples, after checking that all of them use defined relations.
+func (r *Relations) WriteTuples(ctx context.Context, writes []models.RelationTuple, deletes []models.RelationTuple) error {
+	const op = "relations.WriteTuples"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	for _, tuple := range append(append([]models.RelationTuple{}, writes...), deletes...) {
+		if err := r.validateTuple(tuple); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := r.tupleSaver.WriteRelationTuples(ctx, writes, deletes); err != nil {
+		log.Error("failed to write relation tuples", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("relation tuples written", slog.Int("writes", len(writes)), slog.Int("deletes", len(deletes)))
+
+	return nil
+}
+
+// includedRelations returns the relation and all relations it includes, directly or indirectly.
+func (r *Relations) includedRelations(namespace string, relation string) []string {
+	relations := r.namespaces[namespace]
+
+	result := []string{relation}
+	seen := map[string]bool{relation: true}
+
+	for i := 0; i < len(result); i++ {
+		for _, include := range relations[result[i]].Includes {
+			if !seen[include] {
+				seen[include] = true
+				result = append(result, include)
+			}
+		}
+	}
+
+	return result
+}
+
+func (r *Relations) validateRelation(namespace string, relation string) error {
+	relations, ok := r.namespaces[namespace]
+	if !ok {
+		return ErrorUnknownNamespace
+	}
+
+	if _, ok := relations[relation]; !ok {
+		return ErrorUnknownRelation
+	}
+
+	return nil
+}
+
+func (r *Relations) validateTuple(tuple models.RelationTuple) error {
+	if tuple.ObjectId == "" {
+		return ErrorInvalidTuple
+	}
+
+	if err := r.validateRelation(tuple.Namespace, tuple.Relation); err != nil {
+		return err
+	}
+
+	subject := tuple.Subject
+	if subject.IsUser() {
+		if subject.Namespace != "" || subject.ObjectId != "" || subject.Relation != "" {
+			return ErrorInvalidTuple
+		}
+
+		return nil
+	}
+
+	if subject.ObjectId == "" {
+		return ErrorInvalidTuple
+	}
+
+	return r.validateRelation(subject.Namespace, subject.Relation)
+}
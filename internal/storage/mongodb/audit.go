@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const auditLogCollection = "auditLog"
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	const op = "storage.mongodb.SaveAuditEvent"
+
+	collection := s.client.Database(s.database).Collection(auditLogCollection)
+
+	if _, err := collection.InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) createAuditLogIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(auditLogCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	})
+
+	return err
+}
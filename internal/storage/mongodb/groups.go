@@ -0,0 +1,275 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const groupsCollection = "groups"
+
+func (s *Storage) SaveGroup(ctx context.Context, group models.Group) error {
+	const op = "storage.mongodb.SaveGroup"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+
+	if _, err := collection.InsertOne(ctx, group); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrorGroupExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Group(ctx context.Context, name string) (models.Group, error) {
+	const op = "storage.mongodb.Group"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": name}
+
+	var group models.Group
+
+	err := collection.FindOne(ctx, filter).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Group{}, fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+		}
+
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return group, nil
+}
+
+// AddUserToGroup makes the user a direct member of the group. Adding a member twice is a no-op.
+func (s *Storage) AddUserToGroup(ctx context.Context, userId string, groupName string) error {
+	const op = "storage.mongodb.AddUserToGroup"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$addToSet": bson.M{"groups": groupName}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveUserFromGroup(ctx context.Context, userId string, groupName string) error {
+	const op = "storage.mongodb.RemoveUserFromGroup"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$pull": bson.M{"groups": groupName}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// AddSubgroup makes the subgroup a member of the group. Adding a member twice is a no-op.
+//
+// Returns storage.ErrorGroupCycle if the group is the subgroup or already one of its members,
+// directly or through other groups. Concurrent calls may still form a cycle, membership
+// resolution doesn't rely on the nesting being acyclic.
+func (s *Storage) AddSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "storage.mongodb.AddSubgroup"
+
+	ancestors, err := s.groupAncestors(ctx, groupName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == subgroupName {
+			return fmt.Errorf("%s: %w", op, storage.ErrorGroupCycle)
+		}
+	}
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": subgroupName}
+	update := bson.M{"$addToSet": bson.M{"groups": groupName}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "storage.mongodb.RemoveSubgroup"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": subgroupName}
+	update := bson.M{"$pull": bson.M{"groups": groupName}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+// GrantGroupPermission adds the permission for the app to the group.
+// An existing grant of the same name and app is replaced, so a permission can be switched between allow and deny.
+func (s *Storage) GrantGroupPermission(ctx context.Context, groupName string, permission models.Permission) error {
+	const op = "storage.mongodb.GrantGroupPermission"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": groupName}
+
+	result, err := collection.UpdateOne(ctx, filter, grantPermissionUpdate(permission))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeGroupPermission(ctx context.Context, groupName string, permission models.Permission) error {
+	const op = "storage.mongodb.RevokeGroupPermission"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": groupName}
+	update := bson.M{"$pull": bson.M{"permissions": bson.M{"name": permission.Name, "appId": permission.AppID}}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+// AssignGroupRole adds the role assignment to the group. Assigning a role twice is a no-op.
+func (s *Storage) AssignGroupRole(ctx context.Context, groupName string, assignment models.RoleAssignment) error {
+	const op = "storage.mongodb.AssignGroupRole"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": groupName}
+	update := bson.M{"$addToSet": bson.M{"roles": assignment}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnassignGroupRole(ctx context.Context, groupName string, assignment models.RoleAssignment) error {
+	const op = "storage.mongodb.UnassignGroupRole"
+
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	filter := bson.M{"name": groupName}
+	update := bson.M{"$pull": bson.M{"roles": bson.M{"role": assignment.Role, "appId": assignment.AppID}}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorGroupNotFound)
+	}
+
+	return nil
+}
+
+// groupAncestors returns the group and all groups it is a member of, directly or through other groups.
+// The nesting is followed without a depth limit, so no cycle is missed.
+func (s *Storage) groupAncestors(ctx context.Context, groupName string) ([]string, error) {
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"name": groupName}}},
+		{{Key: "$graphLookup", Value: bson.M{
+			"from":             groupsCollection,
+			"startWith":        bson.M{"$ifNull": bson.A{"$groups", bson.A{}}},
+			"connectFromField": "groups",
+			"connectToField":   "name",
+			"as":               "ancestors",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"name":      1,
+			"ancestors": "$ancestors.name",
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Name      string   `bson:"name"`
+		Ancestors []string `bson:"ancestors"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, storage.ErrorGroupNotFound
+	}
+
+	return append([]string{results[0].Name}, results[0].Ancestors...), nil
+}
+
+func (s *Storage) createGroupIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(groupsCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"auth-sso/internal/storage"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+func (s *Storage) SaveMFAPendingSecret(ctx context.Context, userId string, secret []byte) error {
+	const op = "storage.mongodb.SaveMFAPendingSecret"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$set": bson.M{"mfa.pendingSecret": secret}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// EnableMFA turns the pending secret into the active one and stores the recovery code digests.
+func (s *Storage) EnableMFA(
+	ctx context.Context,
+	userId string,
+	secret []byte,
+	step int64,
+	recoveryCodes [][]byte,
+) error {
+	const op = "storage.mongodb.EnableMFA"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{
+		"$set": bson.M{
+			"mfa.enabled":       true,
+			"mfa.secret":        secret,
+			"mfa.lastUsedStep":  step,
+			"mfa.recoveryCodes": recoveryCodes,
+			"mfa.enrolledAt":    time.Now(),
+		},
+		"$unset": bson.M{"mfa.pendingSecret": ""},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// UseMFAStep records the time step of an accepted code.
+// Returns storage.ErrorMFACodeUsed if a code of the same or a later step was accepted before.
+func (s *Storage) UseMFAStep(ctx context.Context, userId string, step int64) error {
+	const op = "storage.mongodb.UseMFAStep"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{
+		"uniqueId": userId,
+		"$or": bson.A{
+			bson.M{"mfa.lastUsedStep": bson.M{"$lt": step}},
+			bson.M{"mfa.lastUsedStep": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"mfa.lastUsedStep": step}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorMFACodeUsed)
+	}
+
+	return nil
+}
+
+// SaveRecoveryCodes replaces all recovery codes of the user.
+func (s *Storage) SaveRecoveryCodes(ctx context.Context, userId string, recoveryCodes [][]byte) error {
+	const op = "storage.mongodb.SaveRecoveryCodes"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$set": bson.M{"mfa.recoveryCodes": recoveryCodes}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code digest, so the code can only be used once.
+// Returns storage.ErrorRecoveryCodeUsed if the user has no such digest.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userId string, recoveryCode []byte) error {
+	const op = "storage.mongodb.UseRecoveryCode"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId, "mfa.recoveryCodes": recoveryCode}
+	update := bson.M{"$pull": bson.M{"mfa.recoveryCodes": recoveryCode}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorRecoveryCodeUsed)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrate brings documents written by earlier versions of the service up to date.
+// Every migration only touches documents which still need it, so running them again is a no-op.
+func (s *Storage) migrate(ctx context.Context) error {
+	if err := s.migrateAppScopedPermissions(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// migrateAppScopedPermissions scopes permissions stored before permissions were app-scoped to all apps,
+// so they keep granting what they granted before.
+func (s *Storage) migrateAppScopedPermissions(ctx context.Context) error {
+	unscoped := bson.M{"appId": bson.M{"$exists": false}}
+	setAllApps := bson.M{"$set": bson.M{"permissions.$[permission].appId": models.AppIDAll}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"permission.appId": bson.M{"$exists": false}}},
+	})
+
+	for _, name := range []string{"users", rolesCollection} {
+		collection := s.client.Database(s.database).Collection(name)
+		filter := bson.M{"permissions": bson.M{"$elemMatch": unscoped}}
+
+		if _, err := collection.UpdateMany(ctx, filter, setAllApps, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
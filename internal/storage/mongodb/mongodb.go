@@ -3,6 +3,7 @@ package mongodb
 import (
 	"auth-sso/internal/domain/models"
 	"auth-sso/internal/storage"
+	"auth-sso/lib/permission"
 	"context"
 	"errors"
 	"fmt"
@@ -20,12 +21,16 @@ const (
 )
 
 type Storage struct {
-	client   *mongo.Client
-	database string
+	client        *mongo.Client
+	database      string
+	maxGroupDepth int
 }
 
 // New creates a new instance of the MongoDB storage.
-func New(uri string, database string) (*Storage, error) {
+//
+// maxGroupDepth is how many levels of nested groups pass their grants on to members,
+// 0 only takes the groups users are direct members of into account.
+func New(uri string, database string, maxGroupDepth int) (*Storage, error) {
 	const op = "storage.mongodb.New"
 
 	clientOptions := options.Client().ApplyURI(uri)
@@ -43,10 +48,63 @@ func New(uri string, database string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{
-		client:   client,
-		database: database,
-	}, nil
+	s := &Storage{
+		client:        client,
+		database:      database,
+		maxGroupDepth: maxGroupDepth,
+	}
+
+	if err := s.createIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.migrate(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// createIndexes makes sure the indexes the storage relies on exist.
+// Creating an index that already exists is a no-op in MongoDB.
+func (s *Storage) createIndexes(ctx context.Context) error {
+	if err := s.createUserIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createRefreshTokenIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createSigningKeyIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createAuditLogIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createOneTimeTokenIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createRoleIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createPolicyIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createRelationTupleIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.createGroupIndexes(ctx); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // Close closes instated mongodb connection.
@@ -229,22 +287,17 @@ func (s *Storage) Validation(ctx context.Context, id string) (models.IdentityVal
 	return result, nil
 }
 
-func (s *Storage) Can(ctx context.Context, permission string, userId string) (bool, error) {
+// Can reports whether the user has the permission for the app, either directly or through one of the assigned roles.
+// Permissions and role assignments scoped to all apps count for every app.
+//
+// Wildcard grants match the permissions below them and deny grants override allows, see permission.Allowed.
+func (s *Storage) Can(ctx context.Context, name string, userId string, appID int) (bool, error) {
 	const op = "storage.mongodb.Can"
 
-	collection := s.client.Database(s.database).Collection("users")
-	filter := bson.M{"uniqueId": userId, "permissions.name": permission}
-
-	var result models.User
-
-	err := collection.FindOne(ctx, filter).Decode(&result)
-
+	grants, err := s.userGrants(ctx, []string{userId}, []string{name}, appID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return false, nil
-		}
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return true, nil
+	return permission.Allowed(grants[userId], name), nil
 }
@@ -0,0 +1,118 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const oneTimeTokensCollection = "oneTimeTokens"
+
+func (s *Storage) SaveOneTimeToken(ctx context.Context, token models.OneTimeToken) error {
+	const op = "storage.mongodb.SaveOneTimeToken"
+
+	collection := s.client.Database(s.database).Collection(oneTimeTokensCollection)
+
+	if _, err := collection.InsertOne(ctx, token); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// OneTimeToken returns the unused, unexpired token without using it.
+func (s *Storage) OneTimeToken(ctx context.Context, tokenHash string, purpose string) (models.OneTimeToken, error) {
+	const op = "storage.mongodb.OneTimeToken"
+
+	collection := s.client.Database(s.database).Collection(oneTimeTokensCollection)
+	filter := bson.M{
+		"tokenHash": tokenHash,
+		"purpose":   purpose,
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	var token models.OneTimeToken
+
+	err := collection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.OneTimeToken{}, fmt.Errorf("%s: %w", op, storage.ErrorOneTimeTokenNotFound)
+		}
+
+		return models.OneTimeToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// UseOneTimeToken marks the unused, unexpired token as used and returns it.
+// Returns storage.ErrorOneTimeTokenNotFound if there is no such token.
+func (s *Storage) UseOneTimeToken(ctx context.Context, tokenHash string, purpose string) (models.OneTimeToken, error) {
+	const op = "storage.mongodb.UseOneTimeToken"
+
+	now := time.Now()
+
+	collection := s.client.Database(s.database).Collection(oneTimeTokensCollection)
+	filter := bson.M{
+		"tokenHash": tokenHash,
+		"purpose":   purpose,
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token models.OneTimeToken
+
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.OneTimeToken{}, fmt.Errorf("%s: %w", op, storage.ErrorOneTimeTokenNotFound)
+		}
+
+		return models.OneTimeToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// DeleteOneTimeTokens removes the user's tokens of the purpose, so only the latest token sent is usable.
+func (s *Storage) DeleteOneTimeTokens(ctx context.Context, userId string, purpose string) error {
+	const op = "storage.mongodb.DeleteOneTimeTokens"
+
+	collection := s.client.Database(s.database).Collection(oneTimeTokensCollection)
+	filter := bson.M{"userId": userId, "purpose": purpose}
+
+	if _, err := collection.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) createOneTimeTokenIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(oneTimeTokensCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
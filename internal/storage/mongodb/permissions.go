@@ -0,0 +1,189 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/lib/permission"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CanBatch evaluates the permission checks for the app like Can, with a single aggregation for all of them.
+// The results are in the order of the checks.
+func (s *Storage) CanBatch(ctx context.Context, checks []models.PermissionCheck, appID int) ([]bool, error) {
+	const op = "storage.mongodb.CanBatch"
+
+	userIds := make([]string, 0, len(checks))
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		userIds = append(userIds, check.UserId)
+		names = append(names, check.Permission)
+	}
+
+	grants, err := s.userGrants(ctx, userIds, names, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	results := make([]bool, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, permission.Allowed(grants[check.UserId], check.Permission))
+	}
+
+	return results, nil
+}
+
+// UserAuthorization returns the roles and grants the user has for the app, see userAuthorizations.
+// An unknown user has none.
+func (s *Storage) UserAuthorization(ctx context.Context, userId string, appID int) (models.UserAuthorization, error) {
+	const op = "storage.mongodb.UserAuthorization"
+
+	authorizations, err := s.userAuthorizations(ctx, []string{userId}, nil, appID)
+	if err != nil {
+		return models.UserAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return authorizations[userId], nil
+}
+
+// userGrants returns the grants of the users for the app which can match one of the permissions,
+// keyed by user ID. Unknown users have no grants.
+func (s *Storage) userGrants(
+	ctx context.Context,
+	userIds []string,
+	names []string,
+	appID int,
+) (map[string][]permission.Grant, error) {
+	candidates := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, candidate := range permission.Candidates(name) {
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	authorizations, err := s.userAuthorizations(ctx, userIds, candidates, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make(map[string][]permission.Grant, len(authorizations))
+	for userId, authorization := range authorizations {
+		for _, grant := range authorization.Permissions {
+			grants[userId] = append(grants[userId], permission.Grant{Name: grant.Name, Deny: grant.Deny})
+		}
+	}
+
+	return grants, nil
+}
+
+// userAuthorizations returns the roles and grants of the users for the app, keyed by user ID.
+// Besides the direct grants these are the grants of the assigned roles and of the groups the users
+// are members of, directly or through up to maxGroupDepth nested groups, including the roles
+// assigned to those groups.
+//
+// If candidates is not nil, only the grants with one of the names are returned.
+func (s *Storage) userAuthorizations(
+	ctx context.Context,
+	userIds []string,
+	candidates []string,
+	appID int,
+) (map[string]models.UserAuthorization, error) {
+	appIDs := bson.A{models.AppIDAll, appID}
+	inScope := func(grant string) bson.M {
+		return bson.M{"$in": bson.A{grant + ".appId", appIDs}}
+	}
+
+	// Names are user input, $literal keeps them from being read as field paths.
+	grantCond := inScope("$$this")
+	if candidates != nil {
+		grantCond = bson.M{"$and": bson.A{
+			grantCond,
+			bson.M{"$in": bson.A{"$$this.name", bson.M{"$literal": candidates}}},
+		}}
+	}
+
+	// flatten concatenates the arrays of the array field, e.g. the permissions of all groups.
+	flatten := func(field string) bson.M {
+		return bson.M{"$reduce": bson.M{
+			"input":        field,
+			"initialValue": bson.A{},
+			"in":           bson.M{"$concatArrays": bson.A{"$$value", bson.M{"$ifNull": bson.A{"$$this", bson.A{}}}}},
+		}}
+	}
+
+	collection := s.client.Database(s.database).Collection("users")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"uniqueId": bson.M{"$in": userIds}}}},
+		// $graphLookup visits every group once, so cyclic nesting ends the traversal.
+		{{Key: "$graphLookup", Value: bson.M{
+			"from":             groupsCollection,
+			"startWith":        bson.M{"$ifNull": bson.A{"$groups", bson.A{}}},
+			"connectFromField": "groups",
+			"connectToField":   "name",
+			"maxDepth":         s.maxGroupDepth,
+			"as":               "memberOf",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"uniqueId": 1,
+			"permissions": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$permissions", bson.A{}}},
+				flatten("$memberOf.permissions"),
+			}},
+			"roles": bson.M{"$filter": bson.M{
+				"input": bson.M{"$concatArrays": bson.A{
+					bson.M{"$ifNull": bson.A{"$roles", bson.A{}}},
+					flatten("$memberOf.roles"),
+				}},
+				"cond": inScope("$$this"),
+			}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         rolesCollection,
+			"localField":   "roles.role",
+			"foreignField": "name",
+			"as":           "assignedRoles",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":      0,
+			"uniqueId": 1,
+			"roles":    "$assignedRoles.name",
+			"grants": bson.M{"$filter": bson.M{
+				"input": bson.M{"$concatArrays": bson.A{
+					"$permissions",
+					flatten("$assignedRoles.permissions"),
+				}},
+				"cond": grantCond,
+			}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		UniqueId string              `bson:"uniqueId"`
+		Roles    []string            `bson:"roles"`
+		Grants   []models.Permission `bson:"grants"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	authorizations := make(map[string]models.UserAuthorization, len(results))
+	for _, result := range results {
+		authorizations[result.UniqueId] = models.UserAuthorization{
+			Roles:       result.Roles,
+			Permissions: result.Grants,
+		}
+	}
+
+	return authorizations, nil
+}
@@ -0,0 +1,53 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/lib/permission"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const policiesCollection = "policies"
+
+// Policies returns the policies of the app, including the ones for all apps,
+// which can apply to one of the permissions.
+func (s *Storage) Policies(ctx context.Context, permissions []string, appID int) ([]models.Policy, error) {
+	const op = "storage.mongodb.Policies"
+
+	var candidates []string
+	for _, name := range permissions {
+		candidates = append(candidates, permission.Candidates(name)...)
+	}
+
+	collection := s.client.Database(s.database).Collection(policiesCollection)
+	filter := bson.M{
+		"appId":      bson.M{"$in": bson.A{models.AppIDAll, appID}},
+		"permission": bson.M{"$in": candidates},
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var policies []models.Policy
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return policies, nil
+}
+
+func (s *Storage) createPolicyIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(policiesCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "permission", Value: 1}, {Key: "appId", Value: 1}},
+		},
+	})
+
+	return err
+}
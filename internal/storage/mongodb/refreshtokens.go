@@ -0,0 +1,121 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const refreshTokensCollection = "refreshTokens"
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.mongodb.SaveRefreshToken"
+
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+
+	if _, err := collection.InsertOne(ctx, token); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	const op = "storage.mongodb.RefreshToken"
+
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+	filter := bson.M{"tokenHash": tokenHash}
+
+	var token models.RefreshToken
+
+	err := collection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrorRefreshTokenNotFound)
+		}
+
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// UseRefreshToken atomically marks the refresh token as used.
+// Returns storage.ErrorRefreshTokenUsed if the token has already been used, so two
+// concurrent refreshes with the same token can never both succeed.
+func (s *Storage) UseRefreshToken(ctx context.Context, tokenId string) error {
+	const op = "storage.mongodb.UseRefreshToken"
+
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+	filter := bson.M{"tokenId": tokenId, "usedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"usedAt": time.Now()}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorRefreshTokenUsed)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	const op = "storage.mongodb.RevokeRefreshTokenFamily"
+
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+	filter := bson.M{"familyId": familyId, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user.
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userId string) error {
+	const op = "storage.mongodb.RevokeUserRefreshTokens"
+
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+	filter := bson.M{"userId": userId, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) createRefreshTokenIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(refreshTokensCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "familyId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,123 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const relationTuplesCollection = "relationTuples"
+
+// WriteRelationTuples deletes and writes relation tuples. Writing an existing tuple and deleting
+// a missing one are no-ops. The deletes are applied before the writes, the changes are not atomic.
+func (s *Storage) WriteRelationTuples(ctx context.Context, writes []models.RelationTuple, deletes []models.RelationTuple) error {
+	const op = "storage.mongodb.WriteRelationTuples"
+
+	collection := s.client.Database(s.database).Collection(relationTuplesCollection)
+
+	if len(deletes) > 0 {
+		operations := make([]mongo.WriteModel, 0, len(deletes))
+		for _, tuple := range deletes {
+			operations = append(operations, mongo.NewDeleteOneModel().SetFilter(bson.M{
+				"namespace": tuple.Namespace,
+				"objectId":  tuple.ObjectId,
+				"relation":  tuple.Relation,
+				"subject":   tuple.Subject,
+			}))
+		}
+
+		if _, err := collection.BulkWrite(ctx, operations); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if len(writes) > 0 {
+		operations := make([]mongo.WriteModel, 0, len(writes))
+		for _, tuple := range writes {
+			operations = append(operations, mongo.NewInsertOneModel().SetDocument(tuple))
+		}
+
+		_, err := collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+		if err != nil && !onlyDuplicateKeyErrors(err) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// RelationTuples returns the tuples of the object with one of the relations.
+// If userId is set, only the tuples of that user and the ones with subject sets are returned.
+func (s *Storage) RelationTuples(
+	ctx context.Context,
+	namespace string,
+	objectId string,
+	relations []string,
+	userId string,
+) ([]models.RelationTuple, error) {
+	const op = "storage.mongodb.RelationTuples"
+
+	collection := s.client.Database(s.database).Collection(relationTuplesCollection)
+	filter := bson.M{
+		"namespace": namespace,
+		"objectId":  objectId,
+		"relation":  bson.M{"$in": relations},
+	}
+
+	if userId != "" {
+		filter["$or"] = bson.A{
+			bson.M{"subject.userId": userId},
+			bson.M{"subject.relation": bson.M{"$exists": true}},
+		}
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var tuples []models.RelationTuple
+	if err := cursor.All(ctx, &tuples); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tuples, nil
+}
+
+func (s *Storage) createRelationTupleIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(relationTuplesCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "namespace", Value: 1},
+				{Key: "objectId", Value: 1},
+				{Key: "relation", Value: 1},
+				{Key: "subject", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+
+	return err
+}
+
+// onlyDuplicateKeyErrors reports whether the bulk write only failed on documents which already exist.
+func onlyDuplicateKeyErrors(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return false
+	}
+
+	for _, e := range bulkErr.WriteErrors {
+		if e.Code != duplicateKeyError {
+			return false
+		}
+	}
+
+	return true
+}
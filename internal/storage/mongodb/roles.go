@@ -0,0 +1,158 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rolesCollection = "roles"
+
+func (s *Storage) SaveRole(ctx context.Context, role models.Role) error {
+	const op = "storage.mongodb.SaveRole"
+
+	collection := s.client.Database(s.database).Collection(rolesCollection)
+
+	if _, err := collection.InsertOne(ctx, role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrorRoleExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Role(ctx context.Context, name string) (models.Role, error) {
+	const op = "storage.mongodb.Role"
+
+	collection := s.client.Database(s.database).Collection(rolesCollection)
+	filter := bson.M{"name": name}
+
+	var role models.Role
+
+	err := collection.FindOne(ctx, filter).Decode(&role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Role{}, fmt.Errorf("%s: %w", op, storage.ErrorRoleNotFound)
+		}
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
+
+// GrantRolePermission adds the permission for the app to the role.
+// An existing grant of the same name and app is replaced, so a permission can be switched between allow and deny.
+func (s *Storage) GrantRolePermission(ctx context.Context, roleName string, permission models.Permission) error {
+	const op = "storage.mongodb.GrantRolePermission"
+
+	collection := s.client.Database(s.database).Collection(rolesCollection)
+	filter := bson.M{"name": roleName}
+
+	result, err := collection.UpdateOne(ctx, filter, grantPermissionUpdate(permission))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorRoleNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeRolePermission(ctx context.Context, roleName string, permission models.Permission) error {
+	const op = "storage.mongodb.RevokeRolePermission"
+
+	collection := s.client.Database(s.database).Collection(rolesCollection)
+	filter := bson.M{"name": roleName}
+	update := bson.M{"$pull": bson.M{"permissions": bson.M{"name": permission.Name, "appId": permission.AppID}}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorRoleNotFound)
+	}
+
+	return nil
+}
+
+// AssignRole adds the role assignment to the user. Assigning a role twice is a no-op.
+func (s *Storage) AssignRole(ctx context.Context, userId string, assignment models.RoleAssignment) error {
+	const op = "storage.mongodb.AssignRole"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$addToSet": bson.M{"roles": assignment}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnassignRole(ctx context.Context, userId string, assignment models.RoleAssignment) error {
+	const op = "storage.mongodb.UnassignRole"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$pull": bson.M{"roles": bson.M{"role": assignment.Role, "appId": assignment.AppID}}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// grantPermissionUpdate adds the permission to the permissions of the document,
+// replacing an existing grant of the same name and app.
+func grantPermissionUpdate(permission models.Permission) bson.A {
+	return bson.A{
+		bson.M{"$set": bson.M{"permissions": bson.M{"$concatArrays": bson.A{
+			bson.M{"$filter": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$permissions", bson.A{}}},
+				"cond": bson.M{"$not": bson.A{bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$$this.name", bson.M{"$literal": permission.Name}}},
+					bson.M{"$eq": bson.A{"$$this.appId", permission.AppID}},
+				}}}},
+			}},
+			bson.A{bson.M{"$literal": permission}},
+		}}}},
+	}
+}
+
+func (s *Storage) createRoleIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(rolesCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+
+	return err
+}
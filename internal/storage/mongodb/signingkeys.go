@@ -0,0 +1,180 @@
+package mongodb
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const signingKeysCollection = "signingKeys"
+
+// SaveSigningKey stores the key as the active key of its app and algorithm, unless the app
+// already has an active key for the algorithm. Returns storage.ErrorSigningKeyExists in that case.
+func (s *Storage) SaveSigningKey(ctx context.Context, key models.SigningKey) error {
+	const op = "storage.mongodb.SaveSigningKey"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	filter := bson.M{
+		"appId":     key.AppID,
+		"algorithm": key.Algorithm,
+		"status":    bson.M{"$nin": bson.A{models.SigningKeyStatusRotated, models.SigningKeyStatusRetired}},
+	}
+	update := bson.M{"$setOnInsert": key}
+	opts := options.Update().SetUpsert(true)
+
+	result, err := collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrorSigningKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.UpsertedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorSigningKeyExists)
+	}
+
+	return nil
+}
+
+// ActiveSigningKey returns the newest active key of the app for the given algorithm.
+// Keys created before key rotation existed have no status and are treated as active.
+func (s *Storage) ActiveSigningKey(ctx context.Context, appID int, algorithm string) (models.SigningKey, error) {
+	const op = "storage.mongodb.ActiveSigningKey"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	filter := bson.M{
+		"appId":     appID,
+		"algorithm": algorithm,
+		"status":    bson.M{"$nin": bson.A{models.SigningKeyStatusRotated, models.SigningKeyStatusRetired}},
+	}
+	opts := options.FindOne().SetSort(bson.M{"createdAt": -1})
+
+	var key models.SigningKey
+
+	err := collection.FindOne(ctx, filter, opts).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, storage.ErrorSigningKeyNotFound)
+		}
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) SigningKeyById(ctx context.Context, keyId string) (models.SigningKey, error) {
+	const op = "storage.mongodb.SigningKeyById"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	filter := bson.M{"keyId": keyId}
+
+	var key models.SigningKey
+
+	err := collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, storage.ErrorSigningKeyNotFound)
+		}
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "storage.mongodb.SigningKeys"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "appId", Value: 1}, {Key: "createdAt", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var keys []models.SigningKey
+
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// MarkSigningKeysRotated moves all other active keys of the app and algorithm into the overlap window.
+func (s *Storage) MarkSigningKeysRotated(
+	ctx context.Context,
+	appID int,
+	algorithm string,
+	activeKeyId string,
+	rotatedAt time.Time,
+) error {
+	const op = "storage.mongodb.MarkSigningKeysRotated"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	filter := bson.M{
+		"appId":     appID,
+		"algorithm": algorithm,
+		"keyId":     bson.M{"$ne": activeKeyId},
+		"status":    bson.M{"$nin": bson.A{models.SigningKeyStatusRotated, models.SigningKeyStatusRetired}},
+	}
+	update := bson.M{"$set": bson.M{"status": models.SigningKeyStatusRotated, "rotatedAt": rotatedAt}}
+
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RetireSigningKey(ctx context.Context, keyId string, retiredAt time.Time) error {
+	const op = "storage.mongodb.RetireSigningKey"
+
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+	filter := bson.M{"keyId": keyId}
+	update := bson.M{"$set": bson.M{"status": models.SigningKeyStatusRetired, "retiredAt": retiredAt}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorSigningKeyNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) createSigningKeyIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection(signingKeysCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "keyId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "appId", Value: 1}, {Key: "algorithm", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			// At most one key per app and algorithm is active.
+			Keys: bson.D{{Key: "appId", Value: 1}, {Key: "algorithm", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": models.SigningKeyStatusActive}),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,103 @@
+package mongodb
+
+import (
+	"auth-sso/internal/storage"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpdatePassword replaces the password hash and the hashes of the previous passwords.
+func (s *Storage) UpdatePassword(ctx context.Context, userId string, passHash []byte, history [][]byte) error {
+	const op = "storage.mongodb.UpdatePassword"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$set": bson.M{"passwordHash": passHash, "passwordHistory": history}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) MarkEmailVerified(ctx context.Context, userId string) error {
+	const op = "storage.mongodb.MarkEmailVerified"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$set": bson.M{"emailVerified": true}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// RehashPassword replaces the password hash, unless the password was changed since oldHash was read.
+func (s *Storage) RehashPassword(ctx context.Context, userId string, oldHash []byte, newHash []byte) error {
+	const op = "storage.mongodb.RehashPassword"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId, "passwordHash": oldHash}
+	update := bson.M{"$set": bson.M{"passwordHash": newHash}}
+
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UpdateEmail replaces the email address of the user. The new address counts as verified.
+// Returns storage.ErrorUserExists if another user has the address.
+func (s *Storage) UpdateEmail(ctx context.Context, userId string, email string) error {
+	const op = "storage.mongodb.UpdateEmail"
+
+	collection := s.client.Database(s.database).Collection("users")
+	filter := bson.M{"uniqueId": userId}
+	update := bson.M{"$set": bson.M{"email": email, "emailVerified": true}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrorUserExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrorUserNotFound)
+	}
+
+	return nil
+}
+
+// createUserIndexes makes email addresses unique, SaveUser and UpdateEmail rely on it to reject taken addresses.
+func (s *Storage) createUserIndexes(ctx context.Context) error {
+	collection := s.client.Database(s.database).Collection("users")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,107 @@
+package policyfile
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/lib/permission"
+	"auth-sso/lib/policy"
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+// Storage serves policies read from a YAML file of the form
+//
+//	policies:
+//	  - id: tenant-isolation
+//	    description: Reviewers only see validations of their own tenant
+//	    app_id: 0
+//	    permission: validations:read
+//	    effect: deny
+//	    condition: subject.tenant != resource.tenant
+//
+// The file is read once, changes need a restart.
+type Storage struct {
+	policies []models.Policy
+}
+
+type file struct {
+	Policies []struct {
+		Id          string `yaml:"id"`
+		Description string `yaml:"description"`
+		AppID       int    `yaml:"app_id"`
+		Permission  string `yaml:"permission"`
+		Effect      string `yaml:"effect"`
+		Condition   string `yaml:"condition"`
+	} `yaml:"policies"`
+}
+
+// New reads and validates the policies of the file.
+func New(path string) (*Storage, error) {
+	const op = "storage.policyfile.New"
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Storage{
+		policies: make([]models.Policy, 0, len(f.Policies)),
+	}
+
+	for i, p := range f.Policies {
+		if p.Id == "" {
+			return nil, fmt.Errorf("%s: policy %d has no id", op, i)
+		}
+
+		if err := permission.Validate(p.Permission); err != nil {
+			return nil, fmt.Errorf("%s: policy %q: %w", op, p.Id, err)
+		}
+
+		if p.Effect != models.PolicyEffectAllow && p.Effect != models.PolicyEffectDeny {
+			return nil, fmt.Errorf("%s: policy %q: unknown effect %q", op, p.Id, p.Effect)
+		}
+
+		if _, err := policy.Compile(p.Condition); err != nil {
+			return nil, fmt.Errorf("%s: policy %q: %w", op, p.Id, err)
+		}
+
+		s.policies = append(s.policies, models.Policy{
+			PolicyId:    p.Id,
+			Description: p.Description,
+			AppID:       p.AppID,
+			Permission:  p.Permission,
+			Effect:      p.Effect,
+			Condition:   p.Condition,
+		})
+	}
+
+	return s, nil
+}
+
+// Policies returns the policies of the app, including the ones for all apps,
+// which can apply to one of the permissions.
+func (s *Storage) Policies(_ context.Context, permissions []string, appID int) ([]models.Policy, error) {
+	var result []models.Policy
+
+	for _, p := range s.policies {
+		if p.AppID != models.AppIDAll && p.AppID != appID {
+			continue
+		}
+
+		for _, name := range permissions {
+			if permission.Matches(p.Permission, name) {
+				result = append(result, p)
+
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
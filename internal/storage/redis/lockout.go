@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"time"
+)
+
+const (
+	loginFailuresPrefix = "auth-sso:login:failures:"
+	loginLockPrefix     = "auth-sso:login:lock:"
+)
+
+// incrementFailuresScript counts a failure and extends the window, so failures are remembered
+// as long as they keep coming in.
+var incrementFailuresScript = goredis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return count
+`)
+
+// IncrementLoginFailures counts a failed login of the subject and returns the failures within the window.
+func (s *Storage) IncrementLoginFailures(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	const op = "storage.redis.IncrementLoginFailures"
+
+	count, err := incrementFailuresScript.Run(
+		ctx,
+		s.client,
+		[]string{loginFailuresPrefix + subject},
+		window.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// LockLogin rejects logins of the subject for the duration.
+func (s *Storage) LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	const op = "storage.redis.LockLogin"
+
+	if err := s.client.Set(ctx, loginLockPrefix+subject, 1, duration).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginLockTTL returns how long logins of the subject stay locked, zero if they are not locked.
+func (s *Storage) LoginLockTTL(ctx context.Context, subject string) (time.Duration, error) {
+	const op = "storage.redis.LoginLockTTL"
+
+	ttl, err := s.client.PTTL(ctx, loginLockPrefix+subject).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Missing keys report a negative TTL.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// ResetLoginFailures forgets the failures of the subject and lifts its lock.
+func (s *Storage) ResetLoginFailures(ctx context.Context, subject string) error {
+	const op = "storage.redis.ResetLoginFailures"
+
+	if err := s.client.Del(ctx, loginFailuresPrefix+subject, loginLockPrefix+subject).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"auth-sso/internal/domain/models"
+	"auth-sso/internal/storage"
+	"context"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	mfaChallengePrefix = "auth-sso:mfa:challenge:"
+)
+
+// incrementAttemptsScript only counts attempts of challenges which still exist,
+// so a challenge expiring between lookup and increment is not recreated without a TTL.
+var incrementAttemptsScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+`)
+
+func (s *Storage) SaveMFAChallenge(
+	ctx context.Context,
+	tokenHash string,
+	challenge models.MFAChallenge,
+	ttl time.Duration,
+) error {
+	const op = "storage.redis.SaveMFAChallenge"
+
+	key := mfaChallengePrefix + tokenHash
+
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, key, "userId", challenge.UserId, "appId", challenge.AppID, "attempts", 0)
+		pipe.Expire(ctx, key, ttl)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) MFAChallenge(ctx context.Context, tokenHash string) (models.MFAChallenge, error) {
+	const op = "storage.redis.MFAChallenge"
+
+	values, err := s.client.HGetAll(ctx, mfaChallengePrefix+tokenHash).Result()
+	if err != nil {
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(values) == 0 {
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrorMFAChallengeNotFound)
+	}
+
+	appID, err := strconv.Atoi(values["appId"])
+	if err != nil {
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.MFAChallenge{
+		UserId: values["userId"],
+		AppID:  appID,
+	}, nil
+}
+
+// IncrementMFAChallengeAttempts counts a verification attempt and returns the number of attempts so far.
+func (s *Storage) IncrementMFAChallengeAttempts(ctx context.Context, tokenHash string) (int64, error) {
+	const op = "storage.redis.IncrementMFAChallengeAttempts"
+
+	attempts, err := incrementAttemptsScript.Run(ctx, s.client, []string{mfaChallengePrefix + tokenHash}).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if attempts < 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrorMFAChallengeNotFound)
+	}
+
+	return attempts, nil
+}
+
+func (s *Storage) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	const op = "storage.redis.DeleteMFAChallenge"
+
+	if err := s.client.Del(ctx, mfaChallengePrefix+tokenHash).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"time"
+)
+
+const requestsPrefix = "auth-sso:requests:"
+
+// countRequestScript counts a request and starts the window with the first one,
+// so the window is not extended by further requests.
+var countRequestScript = goredis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// CountRequest counts a request of the subject and returns the requests within the current window.
+func (s *Storage) CountRequest(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	const op = "storage.redis.CountRequest"
+
+	count, err := countRequestScript.Run(
+		ctx,
+		s.client,
+		[]string{requestsPrefix + subject},
+		window.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"time"
+)
+
+const (
+	revokedTokenPrefix = "auth-sso:revoked:jti:"
+	revokedUserPrefix  = "auth-sso:revoked:user:"
+)
+
+type Storage struct {
+	client *goredis.Client
+}
+
+// New creates a new instance of the Redis storage.
+func New(address string) (*Storage, error) {
+	const op = "storage.redis.New"
+
+	client := goredis.NewClient(&goredis.Options{Addr: address})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{
+		client: client,
+	}, nil
+}
+
+// Close closes instated redis connection.
+func (s *Storage) Close() error {
+	if s.client != nil {
+		return s.client.Close()
+	}
+	return nil
+}
+
+// RevokeToken adds the token id to the revocation list.
+// The entry expires together with the token, so the list only holds tokens that would still be accepted.
+func (s *Storage) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	const op = "storage.redis.RevokeToken"
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := s.client.Set(ctx, revokedTokenPrefix+tokenId, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token id is on the revocation list.
+func (s *Storage) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	const op = "storage.redis.IsTokenRevoked"
+
+	count, err := s.client.Exists(ctx, revokedTokenPrefix+tokenId).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count > 0, nil
+}
+
+// RevokeUserTokens revokes every token of the user issued before the given time.
+// The entry expires after ttl, which must cover the lifetime of the tokens.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userId string, before time.Time, ttl time.Duration) error {
+	const op = "storage.redis.RevokeUserTokens"
+
+	if err := s.client.Set(ctx, revokedUserPrefix+userId, before.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserTokensRevokedBefore returns the time tokens of the user issued earlier are revoked,
+// or the zero time if none are.
+func (s *Storage) UserTokensRevokedBefore(ctx context.Context, userId string) (time.Time, error) {
+	const op = "storage.redis.UserTokensRevokedBefore"
+
+	before, err := s.client.Get(ctx, revokedUserPrefix+userId).Int64()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return time.Unix(before, 0), nil
+}
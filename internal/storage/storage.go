@@ -9,4 +9,23 @@ var (
 	ErrorUserNotFound       = errors.New("user not found")
 	ErrorAppNotFound        = errors.New("app not found")
 	ErrorValidationNotFound = errors.New("validation not found")
+
+	ErrorRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrorRefreshTokenUsed     = errors.New("refresh token already used")
+
+	ErrorSigningKeyNotFound = errors.New("signing key not found")
+	ErrorSigningKeyExists   = errors.New("active signing key already exists")
+
+	ErrorMFAChallengeNotFound = errors.New("mfa challenge not found")
+	ErrorMFACodeUsed          = errors.New("mfa code already used")
+	ErrorRecoveryCodeUsed     = errors.New("recovery code already used")
+
+	ErrorOneTimeTokenNotFound = errors.New("one-time token not found")
+
+	ErrorRoleExists   = errors.New("role already exists")
+	ErrorRoleNotFound = errors.New("role not found")
+
+	ErrorGroupExists   = errors.New("group already exists")
+	ErrorGroupNotFound = errors.New("group not found")
+	ErrorGroupCycle    = errors.New("group nesting would form a cycle")
 )
@@ -1,10 +1,27 @@
 package tasks
 
 import (
+	"auth-sso/internal/tasks/handlers/email"
 	"auth-sso/internal/tasks/handlers/identity"
+	"auth-sso/internal/tasks/handlers/keys"
 	"github.com/hibiken/asynq"
+	"time"
 )
 
-func SetupTaskHandlers(mux *asynq.ServeMux) {
+func SetupTaskHandlers(mux *asynq.ServeMux, keyRotator keys.Rotator, mailSender email.Sender) {
 	mux.HandleFunc(identity.TaskIdentifier, identity.HandleIdentityVerificationTask)
+	mux.HandleFunc(keys.TaskIdentifier, keys.NewRotationHandler(keyRotator))
+	mux.HandleFunc(email.TaskIdentifier, email.NewSendHandler(mailSender))
+}
+
+// SetupScheduledTasks registers the periodic tasks.
+// Every instance runs a scheduler, the uniqueness option keeps them from enqueueing the same task twice.
+func SetupScheduledTasks(scheduler *asynq.Scheduler, keyRotationSchedule string) error {
+	_, err := scheduler.Register(
+		keyRotationSchedule,
+		asynq.NewTask(keys.TaskIdentifier, nil),
+		asynq.Unique(time.Minute),
+	)
+
+	return err
 }
@@ -0,0 +1,38 @@
+package email
+
+import (
+	"auth-sso/lib/mail"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+)
+
+const TaskIdentifier = "email:send"
+
+type SendTaskPayload struct {
+	Message mail.Message
+}
+
+type Sender interface {
+	Send(message mail.Message) error
+}
+
+// NewSendHandler returns a handler delivering the email of the task.
+func NewSendHandler(sender Sender) asynq.HandlerFunc {
+	return func(ctx context.Context, task *asynq.Task) error {
+		const op = "tasks.handlers.email.HandleSendTask"
+
+		var payload SendTaskPayload
+
+		if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := sender.Send(payload.Message); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,26 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"github.com/hibiken/asynq"
+)
+
+const TaskIdentifier = "keys:rotate"
+
+type Rotator interface {
+	RotateExpired(ctx context.Context) error
+}
+
+// NewRotationHandler returns a handler rotating signing keys which are due for rotation.
+func NewRotationHandler(rotator Rotator) asynq.HandlerFunc {
+	return func(ctx context.Context, task *asynq.Task) error {
+		const op = "tasks.handlers.keys.HandleRotationTask"
+
+		if err := rotator.RotateExpired(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+}
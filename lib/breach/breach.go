@@ -0,0 +1,160 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// maxLineLength bounds a line of the corpus: 40 hex characters, a colon and the count.
+const maxLineLength = 128
+
+var ErrorMalformedCorpus = errors.New("malformed breached passwords corpus")
+
+// Checker looks up passwords in a local copy of the "Pwned Passwords" corpus.
+//
+// The corpus is the SHA-1 version of the list, one "HASH:COUNT" line per password, sorted by hash.
+// Lookups binary search the file directly, so it is neither loaded into memory nor indexed up front.
+type Checker struct {
+	file *os.File
+	size int64
+}
+
+// Open opens the corpus at path. The file stays open until Close.
+func Open(path string) (*Checker, error) {
+	const op = "breach.Open"
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Checker{
+		file: file,
+		size: info.Size(),
+	}, nil
+}
+
+func (c *Checker) Close() error {
+	return c.file.Close()
+}
+
+// Count returns how often the password appears in the corpus, 0 if it does not.
+func (c *Checker) Count(password string) (int, error) {
+	const op = "breach.Count"
+
+	sum := sha1.Sum([]byte(password))
+	target := []byte(hex.EncodeToString(sum[:]))
+	target = bytes.ToUpper(target)
+
+	// Searches the lines starting within [lo, hi).
+	lo, hi := int64(0), c.size
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := c.lineFrom(mid)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if start >= hi {
+			hi = mid
+
+			continue
+		}
+
+		hash, count, err := parseLine(line)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		switch cmp := bytes.Compare(bytes.ToUpper(hash), target); {
+		case cmp == 0:
+			return count, nil
+		case cmp < 0:
+			lo = start + 1
+		default:
+			hi = start
+		}
+	}
+
+	return 0, nil
+}
+
+// lineFrom returns the first line starting at or after offset together with its start.
+// The start is the size of the file if there is no such line.
+func (c *Checker) lineFrom(offset int64) (int64, []byte, error) {
+	start := offset
+
+	if offset > 0 {
+		// The line starts after the first line break at or after offset-1.
+		buf, err := c.read(offset - 1)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			if offset-1+int64(len(buf)) >= c.size {
+				return c.size, nil, nil
+			}
+
+			return 0, nil, ErrorMalformedCorpus
+		}
+
+		start = offset + int64(i)
+	}
+
+	if start >= c.size {
+		return c.size, nil, nil
+	}
+
+	buf, err := c.read(start)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	}
+
+	return start, bytes.TrimRight(buf, "\r"), nil
+}
+
+func (c *Checker) read(offset int64) ([]byte, error) {
+	buf := make([]byte, maxLineLength)
+
+	n, err := c.file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func parseLine(line []byte) ([]byte, int, error) {
+	hash, countStr, found := bytes.Cut(line, []byte(":"))
+	if !found || len(hash) != sha1.Size*2 {
+		return nil, 0, ErrorMalformedCorpus
+	}
+
+	count, err := strconv.Atoi(string(bytes.TrimSpace(countStr)))
+	if err != nil {
+		return nil, 0, ErrorMalformedCorpus
+	}
+
+	return hash, count, nil
+}
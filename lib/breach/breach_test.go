@@ -0,0 +1,167 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeCorpus writes the passwords as a sorted corpus, every password appears as often as its index plus one.
+func writeCorpus(t *testing.T, passwords []string, lineBreak string, trailingBreak bool) (string, map[string]int) {
+	t.Helper()
+
+	counts := make(map[string]int, len(passwords))
+	lines := make([]string, 0, len(passwords))
+
+	for i, password := range passwords {
+		counts[password] = i + 1
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(password), i+1))
+	}
+
+	sort.Strings(lines)
+
+	content := strings.Join(lines, lineBreak)
+	if trailingBreak && len(lines) > 0 {
+		content += lineBreak
+	}
+
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+
+	return path, counts
+}
+
+func openCorpus(t *testing.T, path string) *Checker {
+	t.Helper()
+
+	checker, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+
+	t.Cleanup(func() { _ = checker.Close() })
+
+	return checker
+}
+
+func TestCount(t *testing.T) {
+	passwords := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+
+	tests := []struct {
+		name          string
+		passwords     []string
+		lineBreak     string
+		trailingBreak bool
+	}{
+		{name: "many lines", passwords: passwords, lineBreak: "\n", trailingBreak: true},
+		{name: "no trailing line break", passwords: passwords, lineBreak: "\n"},
+		{name: "crlf line breaks", passwords: passwords, lineBreak: "\r\n", trailingBreak: true},
+		{name: "single line", passwords: passwords[:1], lineBreak: "\n", trailingBreak: true},
+		{name: "two lines", passwords: passwords[:2], lineBreak: "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, counts := writeCorpus(t, tt.passwords, tt.lineBreak, tt.trailingBreak)
+			checker := openCorpus(t, path)
+
+			for password, want := range counts {
+				got, err := checker.Count(password)
+				if err != nil {
+					t.Fatalf("Count(%q) = %v", password, err)
+				}
+
+				if got != want {
+					t.Fatalf("Count(%q) = %d, want %d", password, got, want)
+				}
+			}
+
+			for _, password := range []string{"", "not breached", "password-1", "zzzzzzzz"} {
+				got, err := checker.Count(password)
+				if err != nil {
+					t.Fatalf("Count(%q) = %v", password, err)
+				}
+
+				if got != 0 {
+					t.Fatalf("Count(%q) = %d, want 0", password, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCountEmptyCorpus(t *testing.T) {
+	path, _ := writeCorpus(t, nil, "\n", false)
+	checker := openCorpus(t, path)
+
+	got, err := checker.Count("password")
+	if err != nil || got != 0 {
+		t.Fatalf("Count() = %d, %v, want 0", got, err)
+	}
+}
+
+func TestCountLowercaseHashes(t *testing.T) {
+	line := strings.ToLower(sha1Hex("password")) + ":42\n"
+
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+
+	checker := openCorpus(t, path)
+
+	got, err := checker.Count("password")
+	if err != nil || got != 42 {
+		t.Fatalf("Count() = %d, %v, want 42", got, err)
+	}
+}
+
+func TestCountMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing count", content: sha1Hex("password") + "\n"},
+		{name: "invalid count", content: sha1Hex("password") + ":many\n"},
+		{name: "short hash", content: "ABCDEF:1\n"},
+		{name: "line too long", content: strings.Repeat("A", 2*maxLineLength) + "\n" + strings.Repeat("B", 2*maxLineLength) + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "corpus.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write corpus: %v", err)
+			}
+
+			checker := openCorpus(t, path)
+
+			if _, err := checker.Count("password"); !errors.Is(err, ErrorMalformedCorpus) {
+				t.Fatalf("Count() = %v, want ErrorMalformedCorpus", err)
+			}
+		})
+	}
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("Open() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,147 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, keyLength)
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{name: "empty", plaintext: []byte{}},
+		{name: "text", plaintext: []byte("JBSWY3DPEHPK3PXP")},
+		{name: "binary", plaintext: bytes.Repeat([]byte{0x00, 0xff}, 1024)},
+	}
+
+	key := testKey(1)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Encrypt(key, tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() = %v", err)
+			}
+
+			if len(tt.plaintext) > 0 && bytes.Contains(sealed, tt.plaintext) {
+				t.Fatal("sealed data contains the plaintext")
+			}
+
+			opened, err := Decrypt(key, sealed)
+			if err != nil {
+				t.Fatalf("Decrypt() = %v", err)
+			}
+
+			if !bytes.Equal(opened, tt.plaintext) {
+				t.Fatalf("Decrypt() = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesRandomNonce(t *testing.T) {
+	key := testKey(1)
+
+	first, err := Encrypt(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() = %v", err)
+	}
+
+	second, err := Encrypt(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() = %v", err)
+	}
+
+	if bytes.Equal(first, second) {
+		t.Fatal("two encryptions of the same plaintext are equal")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	key := testKey(1)
+
+	sealed, err := Encrypt(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() = %v", err)
+	}
+
+	flip := func(i int) []byte {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x01
+
+		return tampered
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		data    []byte
+		wantErr error
+	}{
+		{name: "tampered nonce", key: key, data: flip(0), wantErr: ErrorInvalidCiphertext},
+		{name: "tampered ciphertext", key: key, data: flip(12), wantErr: ErrorInvalidCiphertext},
+		{name: "tampered tag", key: key, data: flip(len(sealed) - 1), wantErr: ErrorInvalidCiphertext},
+		{name: "truncated", key: key, data: sealed[:len(sealed)-1], wantErr: ErrorInvalidCiphertext},
+		{name: "shorter than nonce", key: key, data: sealed[:4], wantErr: ErrorInvalidCiphertext},
+		{name: "empty", key: key, data: nil, wantErr: ErrorInvalidCiphertext},
+		{name: "other key", key: testKey(2), data: sealed, wantErr: ErrorInvalidCiphertext},
+		{name: "short key", key: key[:16], data: sealed, wantErr: ErrorInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.key, tt.data); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decrypt() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptInvalidKey(t *testing.T) {
+	if _, err := Encrypt(testKey(1)[:31], []byte("secret")); !errors.Is(err, ErrorInvalidKey) {
+		t.Fatalf("Encrypt() = %v, want ErrorInvalidKey", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{name: "valid", encoded: base64.StdEncoding.EncodeToString(testKey(7))},
+		{name: "too short", encoded: base64.StdEncoding.EncodeToString(testKey(7)[:16]), wantErr: true},
+		{name: "too long", encoded: base64.StdEncoding.EncodeToString(append(testKey(7), 0)), wantErr: true},
+		{name: "not base64", encoded: "not base64!", wantErr: true},
+		{name: "empty", encoded: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey(tt.encoded)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrorInvalidKey) {
+					t.Fatalf("ParseKey() = %v, want ErrorInvalidKey", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseKey() = %v", err)
+			}
+
+			if !bytes.Equal(key, testKey(7)) {
+				t.Fatalf("ParseKey() = %x, want %x", key, testKey(7))
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package grpcauth
+
+import (
+	"auth-sso/lib/jwt"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+type principalKey struct{}
+
+// Verifier validates an access token and returns its claims.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (jwt.Claims, error)
+}
+
+// VerifierFunc adapts a function to the Verifier interface.
+type VerifierFunc func(ctx context.Context, token string) (jwt.Claims, error)
+
+func (f VerifierFunc) Verify(ctx context.Context, token string) (jwt.Claims, error) {
+	return f(ctx, token)
+}
+
+// RequireFunc reports whether the full gRPC method name requires an authenticated caller.
+type RequireFunc func(fullMethod string) bool
+
+// RequireAll requires authentication for every method.
+func RequireAll(string) bool {
+	return true
+}
+
+// RequireMethods requires authentication for the given methods only.
+// Methods are matched by their full name ("/package.Service/Method") or by their bare name ("Method").
+func RequireMethods(methods ...string) RequireFunc {
+	required := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		required[method] = true
+	}
+
+	return func(fullMethod string) bool {
+		if required[fullMethod] {
+			return true
+		}
+
+		return required[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+	}
+}
+
+// UnaryServerInterceptor verifies the bearer token of incoming calls and stores the
+// principal in the context. Calls to methods which require authentication are
+// rejected if the token is missing or invalid, other calls proceed without a principal.
+func UnaryServerInterceptor(verifier Verifier, require RequireFunc) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authenticate(ctx, verifier, require(info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(verifier Verifier, require RequireFunc) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authenticate(stream.Context(), verifier, require(info.FullMethod))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// PrincipalFromContext returns the claims of the authenticated caller.
+func PrincipalFromContext(ctx context.Context) (jwt.Claims, bool) {
+	claims, ok := ctx.Value(principalKey{}).(jwt.Claims)
+
+	return claims, ok
+}
+
+// ContextWithPrincipal returns a copy of the context carrying the principal.
+func ContextWithPrincipal(ctx context.Context, claims jwt.Claims) context.Context {
+	return context.WithValue(ctx, principalKey{}, claims)
+}
+
+// TokenFromContext returns the bearer token of the incoming call.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get(authorizationHeader) {
+		if len(value) > len(bearerPrefix) && strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+			return strings.TrimSpace(value[len(bearerPrefix):]), true
+		}
+	}
+
+	return "", false
+}
+
+func authenticate(ctx context.Context, verifier Verifier, required bool) (context.Context, error) {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		if required {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		return ctx, nil
+	}
+
+	claims, err := verifier.Verify(ctx, token)
+	if err != nil {
+		if required {
+			return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+
+		return ctx, nil
+	}
+
+	return ContextWithPrincipal(ctx, claims), nil
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,36 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrorUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+	ErrorMalformedHash        = errors.New("malformed password hash")
+)
+
+// Hasher hashes passwords with one algorithm and set of parameters.
+type Hasher interface {
+	Hash(password string) ([]byte, error)
+	// NeedsRehash reports whether the hash was created with another algorithm or other parameters.
+	NeedsRehash(hash []byte) bool
+}
+
+// Verify checks the password against a hash of any supported algorithm.
+// The algorithm and its parameters are read from the hash.
+func Verify(hash []byte, password string) (bool, error) {
+	switch {
+	case isBcrypt(hash):
+		return verifyBcrypt(hash, password)
+	case strings.HasPrefix(string(hash), argon2idPrefix):
+		return verifyArgon2id(hash, password)
+	}
+
+	return false, ErrorUnsupportedAlgorithm
+}
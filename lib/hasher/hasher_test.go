@@ -0,0 +1,158 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Cheap parameters keep the tests fast, the production defaults are set in the config.
+var testArgon2id = Argon2id{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var testBcrypt = Bcrypt{Cost: 4}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{name: "argon2id", hasher: testArgon2id, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{name: "bcrypt", hasher: testBcrypt, prefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash() = %v", err)
+			}
+
+			if !strings.HasPrefix(string(hash), tt.prefix) {
+				t.Fatalf("Hash() = %q, want prefix %q", hash, tt.prefix)
+			}
+
+			if ok, err := Verify(hash, "correct horse"); !ok || err != nil {
+				t.Fatalf("Verify() with the password = %v, %v, want true", ok, err)
+			}
+
+			if ok, err := Verify(hash, "battery staple"); ok || err != nil {
+				t.Fatalf("Verify() with another password = %v, %v, want false", ok, err)
+			}
+
+			if tt.hasher.NeedsRehash(hash) {
+				t.Fatal("NeedsRehash() of a fresh hash = true")
+			}
+		})
+	}
+}
+
+func TestArgon2idSalted(t *testing.T) {
+	first, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+
+	second, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+
+	if string(first) == string(second) {
+		t.Fatal("two hashes of the same password are equal")
+	}
+}
+
+func TestArgon2idDecode(t *testing.T) {
+	hash, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+
+	decoded, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatalf("decodeArgon2id() = %v", err)
+	}
+
+	if decoded.params != testArgon2id {
+		t.Fatalf("decoded parameters = %+v, want %+v", decoded.params, testArgon2id)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{name: "empty", hash: "", wantErr: ErrorUnsupportedAlgorithm},
+		{name: "plain text", hash: "password", wantErr: ErrorUnsupportedAlgorithm},
+		{name: "unknown algorithm", hash: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5", wantErr: ErrorUnsupportedAlgorithm},
+		{name: "missing key", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ", wantErr: ErrorMalformedHash},
+		{name: "empty key", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$", wantErr: ErrorMalformedHash},
+		{name: "wrong version", hash: "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5", wantErr: ErrorMalformedHash},
+		{name: "bad parameters", hash: "$argon2id$v=19$memory=64$c2FsdHNhbHQ$a2V5a2V5", wantErr: ErrorMalformedHash},
+		{name: "bad salt", hash: "$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5a2V5", wantErr: ErrorMalformedHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Verify([]byte(tt.hash), "password")
+			if ok || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify(%q) = %v, %v, want %v", tt.hash, ok, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2idHash, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+
+	bcryptHash, err := testBcrypt.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+
+	withParams := func(change func(a *Argon2id)) Argon2id {
+		params := testArgon2id
+		change(&params)
+
+		return params
+	}
+
+	tests := []struct {
+		name   string
+		hasher Hasher
+		hash   []byte
+		want   bool
+	}{
+		{name: "argon2id same parameters", hasher: testArgon2id, hash: argon2idHash, want: false},
+		{name: "argon2id memory", hasher: withParams(func(a *Argon2id) { a.Memory = 128 }), hash: argon2idHash, want: true},
+		{name: "argon2id iterations", hasher: withParams(func(a *Argon2id) { a.Iterations = 2 }), hash: argon2idHash, want: true},
+		{name: "argon2id parallelism", hasher: withParams(func(a *Argon2id) { a.Parallelism = 2 }), hash: argon2idHash, want: true},
+		{name: "argon2id salt length", hasher: withParams(func(a *Argon2id) { a.SaltLength = 32 }), hash: argon2idHash, want: true},
+		{name: "argon2id key length", hasher: withParams(func(a *Argon2id) { a.KeyLength = 64 }), hash: argon2idHash, want: true},
+		{name: "argon2id from bcrypt", hasher: testArgon2id, hash: bcryptHash, want: true},
+		{name: "argon2id from malformed", hasher: testArgon2id, hash: []byte("$argon2id$"), want: true},
+		{name: "bcrypt same cost", hasher: testBcrypt, hash: bcryptHash, want: false},
+		{name: "bcrypt other cost", hasher: Bcrypt{Cost: 5}, hash: bcryptHash, want: true},
+		{name: "bcrypt from argon2id", hasher: testBcrypt, hash: argon2idHash, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Fatalf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
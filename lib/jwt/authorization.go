@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"auth-sso/lib/permission"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+)
+
+// Authorization are the roles and permission grants of the user which are embedded in a token,
+// so services can check permissions without calling Authorize.
+//
+// Embedded permissions are a snapshot taken when the token is issued. Conditions of authorization
+// policies are not reflected, permissions guarded by policies still have to be checked with Authorize.
+type Authorization struct {
+	Roles       []string
+	Permissions []permission.Grant
+	// HashOnly embeds only the authz_hash claim instead of the roles and permissions.
+	HashOnly bool
+	// MaxSize is the maximum size in bytes of the embedded roles and permissions,
+	// larger sets are embedded as authz_hash only. Zero means no limit.
+	MaxSize int
+}
+
+// authorizationClaims are the claims roles and permissions are embedded as.
+// Denied permissions are listed separately, they override the allowed ones.
+type authorizationClaims struct {
+	Roles             []string `json:"roles"`
+	Permissions       []string `json:"permissions"`
+	DeniedPermissions []string `json:"denied_permissions,omitempty"`
+}
+
+// AuthorizationHash returns a compact hash of the roles and permission grants. It only changes if the set
+// of roles or grants changes, so it can key caches of authorization decisions.
+func AuthorizationHash(roles []string, grants []permission.Grant) string {
+	encoded, _ := json.Marshal(newAuthorizationClaims(roles, grants))
+
+	return hashAuthorization(encoded)
+}
+
+// addAuthorizationClaims adds the authz_hash claim and, unless they are too large or only the hash
+// is requested, the roles and permissions claims.
+func addAuthorizationClaims(claims map[string]interface{}, authorization Authorization) {
+	embedded := newAuthorizationClaims(authorization.Roles, authorization.Permissions)
+
+	encoded, _ := json.Marshal(embedded)
+
+	claims["authz_hash"] = hashAuthorization(encoded)
+
+	if authorization.HashOnly || (authorization.MaxSize > 0 && len(encoded) > authorization.MaxSize) {
+		return
+	}
+
+	claims["roles"] = embedded.Roles
+	claims["permissions"] = embedded.Permissions
+
+	if len(embedded.DeniedPermissions) > 0 {
+		claims["denied_permissions"] = embedded.DeniedPermissions
+	}
+}
+
+func hashAuthorization(encoded []byte) string {
+	sum := sha256.Sum256(encoded)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// newAuthorizationClaims sorts and deduplicates the roles and grants, so equal sets encode equally.
+func newAuthorizationClaims(roles []string, grants []permission.Grant) authorizationClaims {
+	var allowed, denied []string
+	for _, grant := range grants {
+		if grant.Deny {
+			denied = append(denied, grant.Name)
+		} else {
+			allowed = append(allowed, grant.Name)
+		}
+	}
+
+	return authorizationClaims{
+		Roles:             uniqueSorted(roles),
+		Permissions:       uniqueSorted(allowed),
+		DeniedPermissions: uniqueSorted(denied),
+	}
+}
+
+func uniqueSorted(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// grantsFromClaims reads the embedded permissions claims. Returns nil if the token has none embedded.
+func grantsFromClaims(claims map[string]interface{}) []permission.Grant {
+	if _, ok := claims["permissions"]; !ok {
+		return nil
+	}
+
+	grants := []permission.Grant{}
+	for _, name := range stringList(claims["permissions"]) {
+		grants = append(grants, permission.Grant{Name: name})
+	}
+
+	for _, name := range stringList(claims["denied_permissions"]) {
+		grants = append(grants, permission.Grant{Name: name, Deny: true})
+	}
+
+	return grants
+}
@@ -2,23 +2,306 @@ package jwt
 
 import (
 	"auth-sso/internal/domain/models"
+	"auth-sso/lib/permission"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS512)
+var (
+	ErrorInvalidToken     = errors.New("invalid token")
+	ErrorTokenExpired     = errors.New("token is expired")
+	ErrorInvalidApp       = errors.New("token was issued for another app")
+	ErrorInvalidAudience  = errors.New("token was issued for another audience")
+	ErrorInvalidIssuer    = errors.New("token was issued by another issuer")
+	ErrorTokenNotYetValid = errors.New("token is not valid yet")
+	ErrorVerifyOptions    = errors.New("issuer and audience are required to verify tokens")
+)
+
+// Claims are the verified claims of an access token.
+type Claims struct {
+	ID            string
+	UserID        string
+	Email         string
+	EmailVerified bool
+	AppID         int
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Scopes        []string
+	// Roles and Permissions are only set if they are embedded in the token, see Authorization.
+	Roles       []string
+	Permissions []permission.Grant
+	// AuthorizationHash is the authz_hash claim, see AuthorizationHash.
+	AuthorizationHash string
+	IssuedAt          time.Time
+	NotBefore         time.Time
+	ExpiresAt         time.Time
+}
+
+// Can reports whether the permissions embedded in the token allow the permission.
+// Tokens without embedded permissions allow nothing, use Authorize for them.
+func (c Claims) Can(name string) bool {
+	return permission.Allowed(c.Permissions, name)
+}
+
+// VerifyOptions are the checks Verify runs on top of the signature and expiration checks.
+// Issuer and Audience are required.
+type VerifyOptions struct {
+	// AppID requires the token to be issued for the app. Zero accepts tokens of any app.
+	AppID int
+	// Audience requires the aud claim to contain one of the values, usually the audience of the app.
+	Audience []string
+	// Issuer requires the iss claim to equal the value.
+	Issuer string
+}
+
+// TokenOptions are the service wide settings of issued tokens.
+type TokenOptions struct {
+	// Issuer is the iss claim, it identifies the service which issued the token.
+	Issuer string
+	// Audience is the aud claim of tokens of apps without their own audience. Empty leaves out the claim.
+	Audience []string
+	// TTL is the lifetime of tokens of apps without their own TTL.
+	TTL time.Duration
+}
+
+// KeyFunc resolves the key a token has been signed with.
+// Tokens signed with a shared secret carry no key ID, so the app ID is passed as well.
+type KeyFunc func(keyID string, appID int) (SigningKey, error)
+
+// NewToken issues an access token of the user for the app.
+//
+// Besides the registered claims iss, sub, aud, iat, nbf and exp the token carries uid and app_id,
+// which verifiers relied on before the registered claims were added. The app can set its own
+// audience and TTL, its claim template adds static claims and user attributes, and may leave out
+// the email. If authorization is not nil, the user's roles and permissions are embedded, see Authorization.
+func NewToken(
+	user models.User,
+	app models.App,
+	key SigningKey,
+	opts TokenOptions,
+	authorization *Authorization,
+) (string, error) {
+	method := jwt.GetSigningMethod(key.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("%w: %s", ErrorUnsupportedAlgorithm, key.Algorithm)
+	}
+
+	token := jwt.New(method)
+
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	duration := opts.TTL
+	if app.TokenTTLSeconds > 0 {
+		duration = time.Duration(app.TokenTTLSeconds) * time.Second
+	}
+
+	audience := opts.Audience
+	if len(app.Audience) > 0 {
+		audience = app.Audience
+	}
+
+	now := time.Now()
 
 	claims := token.Claims.(jwt.MapClaims)
+	addTemplateClaims(claims, user, app.Claims)
+
+	claims["jti"] = uuid.New().String()
+	claims["sub"] = user.UniqueId
 	claims["uid"] = user.UniqueId
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
 	claims["app_id"] = app.AppID
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
+	if opts.Issuer != "" {
+		claims["iss"] = opts.Issuer
+	}
+
+	// A single audience is a plain string, as most verifiers expect.
+	switch len(audience) {
+	case 0:
+	case 1:
+		claims["aud"] = audience[0]
+	default:
+		claims["aud"] = audience
+	}
+
+	if !app.Claims.OmitEmail {
+		claims["email"] = user.Email
+	}
+
+	if app.EmailVerifiedClaim {
+		claims["email_verified"] = user.EmailVerified
+	}
+
+	if authorization != nil {
+		addAuthorizationClaims(claims, *authorization)
+	}
+
+	tokenString, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// Verify validates the token signature, expiration, not before time, app, audience and issuer and returns its claims.
+func Verify(tokenString string, keyFunc KeyFunc, opts VerifyOptions) (Claims, error) {
+	claims, err := Parse(tokenString, keyFunc)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if err := claims.Validate(opts); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// Validate checks the app, audience and issuer of parsed claims.
+func (c Claims) Validate(opts VerifyOptions) error {
+	if opts.Issuer == "" || len(opts.Audience) == 0 {
+		return ErrorVerifyOptions
+	}
+
+	if opts.AppID != 0 && c.AppID != opts.AppID {
+		return ErrorInvalidApp
+	}
+
+	if !containsAny(c.Audience, opts.Audience) {
+		return ErrorInvalidAudience
+	}
+
+	if c.Issuer != opts.Issuer {
+		return ErrorInvalidIssuer
+	}
+
+	return nil
+}
+
+// Parse validates the token signature, expiration and not before time and returns its claims.
+func Parse(tokenString string, keyFunc KeyFunc) (Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, ErrorInvalidToken
+		}
+
+		keyID, _ := token.Header["kid"].(string)
+		appID, _ := claims["app_id"].(float64)
+
+		key, err := keyFunc(keyID, int(appID))
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm is bound to the key, never taken from the token header alone.
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+
+		return key.verificationKey(), nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return Claims{}, ErrorTokenExpired
+		}
+
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorNotValidYet != 0 {
+			return Claims{}, ErrorTokenNotYetValid
+		}
+
+		return Claims{}, fmt.Errorf("%w: %s", ErrorInvalidToken, err.Error())
+	}
+
+	return claimsFromMap(token.Claims.(jwt.MapClaims))
+}
+
+func claimsFromMap(claims jwt.MapClaims) (Claims, error) {
+	id, _ := claims["jti"].(string)
+	userID, _ := claims["uid"].(string)
+	subject, _ := claims["sub"].(string)
+	issuer, _ := claims["iss"].(string)
+	nbf, _ := claims["nbf"].(float64)
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	appID, _ := claims["app_id"].(float64)
+	exp, _ := claims["exp"].(float64)
+	iat, _ := claims["iat"].(float64)
+	scope, _ := claims["scope"].(string)
+	authorizationHash, _ := claims["authz_hash"].(string)
+
+	if id == "" || userID == "" || exp == 0 {
+		return Claims{}, ErrorInvalidToken
+	}
+
+	// Tokens issued before the iat claim was introduced keep the zero time.
+	var issuedAt time.Time
+	if iat != 0 {
+		issuedAt = time.Unix(int64(iat), 0)
+	}
+
+	// The same goes for nbf.
+	var notBefore time.Time
+	if nbf != 0 {
+		notBefore = time.Unix(int64(nbf), 0)
+	}
+
+	return Claims{
+		ID:                id,
+		UserID:            userID,
+		Email:             email,
+		EmailVerified:     emailVerified,
+		AppID:             int(appID),
+		Issuer:            issuer,
+		Subject:           subject,
+		Audience:          stringList(claims["aud"]),
+		Scopes:            strings.Fields(scope),
+		Roles:             stringList(claims["roles"]),
+		Permissions:       grantsFromClaims(claims),
+		AuthorizationHash: authorizationHash,
+		IssuedAt:          issuedAt,
+		NotBefore:         notBefore,
+		ExpiresAt:         time.Unix(int64(exp), 0),
+	}, nil
+}
+
+// stringList reads a claim which may either be a single string or an array of strings.
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, v := range value {
+			if str, ok := v.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
+
+func containsAny(values []string, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
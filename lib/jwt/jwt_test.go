@@ -0,0 +1,325 @@
+package jwt
+
+import (
+	"auth-sso/internal/domain/models"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "api.example.com"
+)
+
+var (
+	testUser = models.User{
+		UniqueId: "4d1f7f5e-5b7e-4a37-9f33-7c5b8c0a9f10",
+		Email:    "alice@example.com",
+	}
+	testApp = models.App{
+		AppID:  7,
+		Secret: "app secret of at least some length",
+	}
+	testTokenOptions = TokenOptions{
+		Issuer:   testIssuer,
+		Audience: []string{testAudience},
+		TTL:      time.Hour,
+	}
+	testVerifyOptions = VerifyOptions{
+		AppID:    testApp.AppID,
+		Issuer:   testIssuer,
+		Audience: []string{testAudience},
+	}
+)
+
+func testKeys(t *testing.T) []SigningKey {
+	t.Helper()
+
+	keys := []SigningKey{{Algorithm: AlgorithmHS512, PrivateKey: []byte(testApp.Secret)}}
+
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		key, err := GenerateKey(algorithm+"-key", algorithm)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) = %v", algorithm, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func keyFuncOf(key SigningKey) KeyFunc {
+	return func(keyID string, appID int) (SigningKey, error) {
+		if keyID != key.ID {
+			return SigningKey{}, errors.New("unknown key")
+		}
+
+		return key, nil
+	}
+}
+
+// signClaims signs arbitrary claims, so tokens NewToken would never issue can be tested.
+func signClaims(t *testing.T, key SigningKey, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signed
+}
+
+// validClaims are the claims of a token NewToken issues for testUser and testApp.
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+
+	return jwt.MapClaims{
+		"jti":    "token-id",
+		"sub":    testUser.UniqueId,
+		"uid":    testUser.UniqueId,
+		"iss":    testIssuer,
+		"aud":    testAudience,
+		"iat":    now.Unix(),
+		"nbf":    now.Unix(),
+		"exp":    now.Add(time.Hour).Unix(),
+		"app_id": testApp.AppID,
+	}
+}
+
+func TestNewTokenVerify(t *testing.T) {
+	for _, key := range testKeys(t) {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			token, err := NewToken(testUser, testApp, key, testTokenOptions, nil)
+			if err != nil {
+				t.Fatalf("NewToken() = %v", err)
+			}
+
+			claims, err := Verify(token, keyFuncOf(key), testVerifyOptions)
+			if err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+
+			if claims.UserID != testUser.UniqueId || claims.Subject != testUser.UniqueId {
+				t.Errorf("uid = %q, sub = %q, want %q", claims.UserID, claims.Subject, testUser.UniqueId)
+			}
+
+			if claims.Email != testUser.Email {
+				t.Errorf("email = %q, want %q", claims.Email, testUser.Email)
+			}
+
+			if claims.AppID != testApp.AppID {
+				t.Errorf("app_id = %d, want %d", claims.AppID, testApp.AppID)
+			}
+
+			if claims.Issuer != testIssuer {
+				t.Errorf("iss = %q, want %q", claims.Issuer, testIssuer)
+			}
+
+			if len(claims.Audience) != 1 || claims.Audience[0] != testAudience {
+				t.Errorf("aud = %v, want [%s]", claims.Audience, testAudience)
+			}
+
+			if claims.ID == "" || claims.IssuedAt.IsZero() || claims.NotBefore.IsZero() {
+				t.Errorf("jti = %q, iat = %v, nbf = %v, want them set", claims.ID, claims.IssuedAt, claims.NotBefore)
+			}
+
+			if ttl := claims.ExpiresAt.Sub(claims.IssuedAt); ttl != time.Hour {
+				t.Errorf("exp - iat = %v, want %v", ttl, time.Hour)
+			}
+		})
+	}
+}
+
+func TestNewTokenAppSettings(t *testing.T) {
+	key := SigningKey{Algorithm: AlgorithmHS512, PrivateKey: []byte(testApp.Secret)}
+
+	app := testApp
+	app.Audience = []string{"billing", "reports"}
+	app.TokenTTLSeconds = 60
+	app.Claims.OmitEmail = true
+
+	token, err := NewToken(testUser, app, key, testTokenOptions, nil)
+	if err != nil {
+		t.Fatalf("NewToken() = %v", err)
+	}
+
+	// The app's audience replaces the default one.
+	_, err = Verify(token, keyFuncOf(key), testVerifyOptions)
+	if !errors.Is(err, ErrorInvalidAudience) {
+		t.Fatalf("Verify() with the default audience = %v, want ErrorInvalidAudience", err)
+	}
+
+	opts := testVerifyOptions
+	opts.Audience = app.Audience
+
+	claims, err := Verify(token, keyFuncOf(key), opts)
+	if err != nil {
+		t.Fatalf("Verify() with the app audience = %v", err)
+	}
+
+	if len(claims.Audience) != 2 {
+		t.Errorf("aud = %v, want %v", claims.Audience, app.Audience)
+	}
+
+	if claims.Email != "" {
+		t.Errorf("email = %q, want it left out", claims.Email)
+	}
+
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt); ttl != time.Minute {
+		t.Errorf("exp - iat = %v, want %v", ttl, time.Minute)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := SigningKey{Algorithm: AlgorithmHS512, PrivateKey: []byte(testApp.Secret)}
+
+	with := func(change func(claims jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		opts    VerifyOptions
+		wantErr error
+	}{
+		{
+			name:    "wrong issuer",
+			claims:  with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidIssuer,
+		},
+		{
+			name:    "missing issuer",
+			claims:  with(func(c jwt.MapClaims) { delete(c, "iss") }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidIssuer,
+		},
+		{
+			name:    "wrong audience",
+			claims:  with(func(c jwt.MapClaims) { c["aud"] = "other.example.com" }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidAudience,
+		},
+		{
+			name:    "wrong audience list",
+			claims:  with(func(c jwt.MapClaims) { c["aud"] = []string{"a.example.com", "b.example.com"} }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidAudience,
+		},
+		{
+			name:    "missing audience",
+			claims:  with(func(c jwt.MapClaims) { delete(c, "aud") }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidAudience,
+		},
+		{
+			name:    "not yet valid",
+			claims:  with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorTokenNotYetValid,
+		},
+		{
+			name:    "expired",
+			claims:  with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorTokenExpired,
+		},
+		{
+			name:    "other app",
+			claims:  with(func(c jwt.MapClaims) { c["app_id"] = testApp.AppID + 1 }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidApp,
+		},
+		{
+			name:    "missing jti",
+			claims:  with(func(c jwt.MapClaims) { delete(c, "jti") }),
+			opts:    testVerifyOptions,
+			wantErr: ErrorInvalidToken,
+		},
+		{
+			name:    "no issuer required",
+			claims:  validClaims(),
+			opts:    VerifyOptions{Audience: []string{testAudience}},
+			wantErr: ErrorVerifyOptions,
+		},
+		{
+			name:    "no audience required",
+			claims:  validClaims(),
+			opts:    VerifyOptions{Issuer: testIssuer},
+			wantErr: ErrorVerifyOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signClaims(t, key, tt.claims)
+
+			if _, err := Verify(token, keyFuncOf(key), tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsAnyMatchingAudience(t *testing.T) {
+	key := SigningKey{Algorithm: AlgorithmHS512, PrivateKey: []byte(testApp.Secret)}
+
+	claims := validClaims()
+	claims["aud"] = []string{"reports.example.com", testAudience}
+
+	opts := testVerifyOptions
+	opts.Audience = []string{"billing.example.com", testAudience}
+
+	if _, err := Verify(signClaims(t, key, claims), keyFuncOf(key), opts); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
+
+func TestVerifyRejectsForeignSignatures(t *testing.T) {
+	keys := testKeys(t)
+
+	for _, key := range keys {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			token, err := NewToken(testUser, testApp, key, testTokenOptions, nil)
+			if err != nil {
+				t.Fatalf("NewToken() = %v", err)
+			}
+
+			for _, other := range keys {
+				if other.Algorithm == key.Algorithm {
+					continue
+				}
+
+				// The token names the key it was signed with, the verifier resolves another one.
+				other.ID = key.ID
+
+				_, err := Verify(token, keyFuncOf(other), testVerifyOptions)
+				if !errors.Is(err, ErrorInvalidToken) {
+					t.Errorf("Verify() with a %s key = %v, want ErrorInvalidToken", other.Algorithm, err)
+				}
+			}
+
+			if key.Algorithm == AlgorithmHS512 {
+				otherSecret := SigningKey{Algorithm: AlgorithmHS512, PrivateKey: []byte("another secret")}
+
+				_, err := Verify(token, keyFuncOf(otherSecret), testVerifyOptions)
+				if !errors.Is(err, ErrorInvalidToken) {
+					t.Errorf("Verify() with another secret = %v, want ErrorInvalidToken", err)
+				}
+			}
+		})
+	}
+}
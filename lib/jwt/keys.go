@@ -0,0 +1,304 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	AlgorithmHS512 = "HS512"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+)
+
+var (
+	ErrorUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// SigningKey is a key tokens are signed and verified with.
+//
+// For HS512 the private key is the shared secret and the key has no ID.
+// For asymmetric algorithms the ID is published as the kid header of the token.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// JWK is the public part of a signing key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// IsAsymmetric reports whether the algorithm uses a private/public key pair.
+func IsAsymmetric(algorithm string) bool {
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA:
+		return true
+	}
+
+	return false
+}
+
+// GenerateKey creates a new key pair for the given asymmetric algorithm.
+func GenerateKey(id string, algorithm string) (SigningKey, error) {
+	var (
+		private crypto.PrivateKey
+		public  crypto.PublicKey
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		private, public = key, &key.PublicKey
+	case AlgorithmES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		private, public = key, &key.PublicKey
+	case AlgorithmEdDSA:
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		private, public = key, pub
+	default:
+		return SigningKey{}, fmt.Errorf("%w: %s", ErrorUnsupportedAlgorithm, algorithm)
+	}
+
+	return SigningKey{
+		ID:         id,
+		Algorithm:  algorithm,
+		PrivateKey: private,
+		PublicKey:  public,
+	}, nil
+}
+
+// EncodePrivateKey returns the private key as a PKCS #8 PEM block.
+func EncodePrivateKey(key SigningKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// DecodePrivateKey restores a key encoded with EncodePrivateKey.
+func DecodePrivateKey(id string, algorithm string, data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, errors.New("failed to decode PEM block")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	var public crypto.PublicKey
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		public = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		public = &key.PublicKey
+	case ed25519.PrivateKey:
+		public = key.Public()
+	default:
+		return SigningKey{}, fmt.Errorf("%w: %T", ErrorUnsupportedAlgorithm, private)
+	}
+
+	if err := checkKeyType(algorithm, public); err != nil {
+		return SigningKey{}, err
+	}
+
+	return SigningKey{
+		ID:         id,
+		Algorithm:  algorithm,
+		PrivateKey: private,
+		PublicKey:  public,
+	}, nil
+}
+
+// JWK returns the public part of the key in JWK format.
+func (k SigningKey) JWK() (JWK, error) {
+	jwk := JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Algorithm,
+	}
+
+	switch public := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(public.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encodeSegment(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(public)
+	default:
+		return JWK{}, fmt.Errorf("%w: %T", ErrorUnsupportedAlgorithm, k.PublicKey)
+	}
+
+	return jwk, nil
+}
+
+// verificationKey returns the key the token signature is checked against.
+func (k SigningKey) verificationKey() interface{} {
+	if k.Algorithm == AlgorithmHS512 {
+		return k.PrivateKey
+	}
+
+	return k.PublicKey
+}
+
+func checkKeyType(algorithm string, public crypto.PublicKey) error {
+	var ok bool
+
+	switch algorithm {
+	case AlgorithmRS256:
+		_, ok = public.(*rsa.PublicKey)
+	case AlgorithmES256:
+		_, ok = public.(*ecdsa.PublicKey)
+	case AlgorithmEdDSA:
+		_, ok = public.(ed25519.PublicKey)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: key does not match %s", ErrorUnsupportedAlgorithm, algorithm)
+	}
+
+	return nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// SigningKey converts the public JWK back into a key tokens can be verified with.
+// The returned key has no private part.
+func (j JWK) SigningKey() (SigningKey, error) {
+	var public crypto.PublicKey
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if j.Crv != elliptic.P256().Params().Name {
+			return SigningKey{}, fmt.Errorf("%w: curve %s", ErrorUnsupportedAlgorithm, j.Crv)
+		}
+
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		public = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "OKP":
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		if j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return SigningKey{}, fmt.Errorf("%w: curve %s", ErrorUnsupportedAlgorithm, j.Crv)
+		}
+
+		public = ed25519.PublicKey(x)
+	default:
+		return SigningKey{}, fmt.Errorf("%w: key type %s", ErrorUnsupportedAlgorithm, j.Kty)
+	}
+
+	if err := checkKeyType(j.Alg, public); err != nil {
+		return SigningKey{}, err
+	}
+
+	return SigningKey{
+		ID:        j.Kid,
+		Algorithm: j.Alg,
+		PublicKey: public,
+	}, nil
+}
+
+// KeySet is a set of public keys indexed by key ID, usually built from the JWKS of the service.
+type KeySet map[string]SigningKey
+
+// NewKeySet builds a key set from JWKs.
+func NewKeySet(jwks []JWK) (KeySet, error) {
+	set := make(KeySet, len(jwks))
+
+	for _, jwk := range jwks {
+		key, err := jwk.SigningKey()
+		if err != nil {
+			return nil, err
+		}
+
+		set[key.ID] = key
+	}
+
+	return set, nil
+}
+
+// KeyFunc resolves keys of the set, so resource servers can verify tokens without any secret.
+// Tokens without a key ID are signed with an app secret and can't be verified with a key set.
+func (s KeySet) KeyFunc(keyID string, appID int) (SigningKey, error) {
+	key, ok := s[keyID]
+	if !ok {
+		return SigningKey{}, fmt.Errorf("%w: unknown key %q", ErrorInvalidToken, keyID)
+	}
+
+	return key, nil
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
@@ -0,0 +1,57 @@
+package jwt
+
+import "auth-sso/internal/domain/models"
+
+// reservedClaims are set by the service itself, claim templates can't override them.
+var reservedClaims = map[string]bool{
+	"jti":                true,
+	"uid":                true,
+	"sub":                true,
+	"iss":                true,
+	"aud":                true,
+	"iat":                true,
+	"nbf":                true,
+	"exp":                true,
+	"app_id":             true,
+	"scope":              true,
+	"email":              true,
+	"email_verified":     true,
+	"roles":              true,
+	"permissions":        true,
+	"denied_permissions": true,
+	"authz_hash":         true,
+}
+
+// addTemplateClaims adds the static claims and the user attributes selected by the template.
+// Attributes the user doesn't have are left out, reserved claim names are skipped.
+func addTemplateClaims(claims map[string]interface{}, user models.User, template models.ClaimTemplate) {
+	for name, value := range template.Static {
+		if !reservedClaims[name] {
+			claims[name] = value
+		}
+	}
+
+	for name, attribute := range template.Attributes {
+		if reservedClaims[name] {
+			continue
+		}
+
+		if value, ok := userAttribute(user, attribute); ok {
+			claims[name] = value
+		}
+	}
+}
+
+// userAttribute returns the email, email_verified or free-form attribute of the user.
+func userAttribute(user models.User, attribute string) (interface{}, bool) {
+	switch attribute {
+	case "email":
+		return user.Email, true
+	case "email_verified":
+		return user.EmailVerified, true
+	}
+
+	value, ok := user.Attributes[attribute]
+
+	return value, ok
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages through an SMTP server.
+type Sender struct {
+	address string
+	auth    smtp.Auth
+	from    string
+}
+
+// New returns a sender for the SMTP server. Authentication is skipped if username is empty.
+func New(host string, port int, username string, password string, from string) *Sender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &Sender{
+		address: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth:    auth,
+		from:    from,
+	}
+}
+
+func (s *Sender) Send(message Message) error {
+	const op = "mail.Send"
+
+	if err := smtp.SendMail(s.address, s.auth, s.from, []string{message.To}, s.build(message)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Sender) build(message Message) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + headerValue(s.from) + "\r\n")
+	b.WriteString("To: " + headerValue(message.To) + "\r\n")
+	b.WriteString("Subject: " + headerValue(message.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// headerValue drops line breaks, so user supplied values can't inject headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,29 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const tokenLength = 32
+
+// NewToken returns a random URL-safe token with 256 bits of entropy.
+func NewToken() (string, error) {
+	buf := make([]byte, tokenLength)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash returns the hex encoded SHA-256 digest of the token.
+// Only the digest is persisted, so a leaked database does not leak usable tokens.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
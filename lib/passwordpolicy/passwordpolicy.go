@@ -0,0 +1,146 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules a password can violate.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "upper"
+	RuleLower     = "lower"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleEmail     = "email"
+	RuleHistory   = "history"
+	RuleBreached  = "breached"
+)
+
+var ErrorPolicyViolation = errors.New("password violates the password policy")
+
+// Policy describes the passwords accepted by the service. Zero values disable a rule.
+type Policy struct {
+	// MinLength is the minimal number of characters.
+	MinLength int
+	// MaxLength is the maximal length in bytes. bcrypt ignores everything past 72 bytes.
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DisallowEmail rejects the user's email address or its local part as password.
+	DisallowEmail bool
+	// History is the number of previous passwords which can't be reused.
+	History int
+}
+
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+// Error carries all the violations of a rejected password.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+
+	return ErrorPolicyViolation.Error() + ": " + strings.Join(descriptions, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return ErrorPolicyViolation
+}
+
+// Validate checks the password against the rules of the policy, except for the history rule
+// which needs the previous password hashes of the user.
+func (p Policy) Validate(password string, email string) []Violation {
+	var violations []Violation
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:        RuleMinLength,
+			Description: fmt.Sprintf("must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:        RuleMaxLength,
+			Description: fmt.Sprintf("must be at most %d bytes long", p.MaxLength),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Rule: RuleUpper, Description: "must contain an uppercase letter"})
+	}
+
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Rule: RuleLower, Description: "must contain a lowercase letter"})
+	}
+
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Rule: RuleDigit, Description: "must contain a digit"})
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Rule: RuleSymbol, Description: "must contain a symbol"})
+	}
+
+	if p.DisallowEmail && email != "" && matchesEmail(password, email) {
+		violations = append(violations, Violation{Rule: RuleEmail, Description: "must not be the email address"})
+	}
+
+	return violations
+}
+
+// HistoryViolation is the violation of a password matching one of the previous passwords.
+func HistoryViolation(history int) Violation {
+	return Violation{
+		Rule:        RuleHistory,
+		Description: fmt.Sprintf("must not be one of the last %d passwords", history),
+	}
+}
+
+// BreachedViolation is the violation of a password found in a list of breached passwords.
+func BreachedViolation() Violation {
+	return Violation{
+		Rule:        RuleBreached,
+		Description: "must not be a password which appeared in a data breach",
+	}
+}
+
+func matchesEmail(password string, email string) bool {
+	if strings.EqualFold(password, email) {
+		return true
+	}
+
+	local, _, found := strings.Cut(email, "@")
+
+	return found && strings.EqualFold(password, local)
+}
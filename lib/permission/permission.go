@@ -0,0 +1,92 @@
+package permission
+
+import (
+	"errors"
+	"strings"
+)
+
+// Permissions are names made of segments separated by Separator, e.g. "documents:read".
+//
+// A grant ending in the Wildcard segment matches every permission below its prefix:
+// "documents:*" matches "documents:read" and "documents:drafts:read", "*" matches everything.
+// The wildcard is only allowed as the last segment.
+const (
+	Separator = ":"
+	Wildcard  = "*"
+)
+
+var ErrorInvalidName = errors.New("invalid permission name")
+
+// Grant allows the permission it names, or denies it if Deny is set.
+type Grant struct {
+	Name string
+	Deny bool
+}
+
+// Validate checks that the name has no empty segments and uses the wildcard only as the last segment.
+func Validate(name string) error {
+	segments := strings.Split(name, Separator)
+
+	for i, segment := range segments {
+		if segment == "" {
+			return ErrorInvalidName
+		}
+
+		if strings.Contains(segment, Wildcard) && (segment != Wildcard || i != len(segments)-1) {
+			return ErrorInvalidName
+		}
+	}
+
+	return nil
+}
+
+// Candidates returns the names of all grants which can match the permission:
+// the permission itself and the wildcard of every prefix, most specific first.
+func Candidates(permission string) []string {
+	segments := strings.Split(permission, Separator)
+
+	candidates := make([]string, 0, len(segments)+1)
+	candidates = append(candidates, permission)
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		candidates = append(candidates, strings.Join(append(segments[:i:i], Wildcard), Separator))
+	}
+
+	return candidates
+}
+
+// Matches reports whether the grant name covers the permission.
+func Matches(grant string, permission string) bool {
+	if grant == permission {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(grant, Wildcard)
+	if !ok {
+		return false
+	}
+
+	return prefix == "" || (strings.HasSuffix(prefix, Separator) && strings.HasPrefix(permission, prefix))
+}
+
+// Allowed decides the permission from the grants.
+//
+// Deny overrides allow: a matching deny grant rejects the permission no matter how specific
+// the matching allow grants are. Without any matching grant the permission is rejected.
+func Allowed(grants []Grant, permission string) bool {
+	allowed := false
+
+	for _, grant := range grants {
+		if !Matches(grant.Name, permission) {
+			continue
+		}
+
+		if grant.Deny {
+			return false
+		}
+
+		allowed = true
+	}
+
+	return allowed
+}
@@ -0,0 +1,180 @@
+package permission
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "documents"},
+		{name: "documents:read"},
+		{name: "documents:drafts:read"},
+		{name: "documents:*"},
+		{name: "*"},
+		{name: "", wantErr: true},
+		{name: "documents:", wantErr: true},
+		{name: ":read", wantErr: true},
+		{name: "documents::read", wantErr: true},
+		{name: "*:read", wantErr: true},
+		{name: "documents:*:read", wantErr: true},
+		{name: "documents:re*", wantErr: true},
+		{name: "documents:**", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.name)
+
+			if tt.wantErr && !errors.Is(err, ErrorInvalidName) {
+				t.Fatalf("Validate(%q) = %v, want ErrorInvalidName", tt.name, err)
+			}
+
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Validate(%q) = %v, want nil", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		permission string
+		want       []string
+	}{
+		{
+			permission: "documents",
+			want:       []string{"documents", "*"},
+		},
+		{
+			permission: "documents:read",
+			want:       []string{"documents:read", "documents:*", "*"},
+		},
+		{
+			permission: "documents:drafts:read",
+			want:       []string{"documents:drafts:read", "documents:drafts:*", "documents:*", "*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			got := Candidates(tt.permission)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Candidates(%q) = %v, want %v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandidatesMatch(t *testing.T) {
+	for _, permission := range []string{"documents", "documents:read", "documents:drafts:read"} {
+		for _, candidate := range Candidates(permission) {
+			if !Matches(candidate, permission) {
+				t.Errorf("candidate %q of %q does not match it", candidate, permission)
+			}
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		grant      string
+		permission string
+		want       bool
+	}{
+		{grant: "documents:read", permission: "documents:read", want: true},
+		{grant: "documents:read", permission: "documents:write", want: false},
+		{grant: "documents:*", permission: "documents:read", want: true},
+		{grant: "documents:*", permission: "documents:drafts:read", want: true},
+		{grant: "documents:*", permission: "documents", want: false},
+		{grant: "documents:*", permission: "documentsx:read", want: false},
+		{grant: "documents:drafts:*", permission: "documents:read", want: false},
+		{grant: "*", permission: "documents:read", want: true},
+		{grant: "*", permission: "documents", want: true},
+		{grant: "documents", permission: "documents:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.grant+" "+tt.permission, func(t *testing.T) {
+			if got := Matches(tt.grant, tt.permission); got != tt.want {
+				t.Fatalf("Matches(%q, %q) = %v, want %v", tt.grant, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		grants     []Grant
+		permission string
+		want       bool
+	}{
+		{
+			name:       "no grants",
+			permission: "documents:read",
+			want:       false,
+		},
+		{
+			name:       "exact allow",
+			grants:     []Grant{{Name: "documents:read"}},
+			permission: "documents:read",
+			want:       true,
+		},
+		{
+			name:       "wildcard allow",
+			grants:     []Grant{{Name: "documents:*"}},
+			permission: "documents:read",
+			want:       true,
+		},
+		{
+			name:       "unrelated allow",
+			grants:     []Grant{{Name: "reports:read"}},
+			permission: "documents:read",
+			want:       false,
+		},
+		{
+			name:       "exact deny overrides wildcard allow",
+			grants:     []Grant{{Name: "documents:*"}, {Name: "documents:delete", Deny: true}},
+			permission: "documents:delete",
+			want:       false,
+		},
+		{
+			name:       "wildcard deny overrides exact allow",
+			grants:     []Grant{{Name: "documents:delete"}, {Name: "documents:*", Deny: true}},
+			permission: "documents:delete",
+			want:       false,
+		},
+		{
+			name:       "deny before allow",
+			grants:     []Grant{{Name: "*", Deny: true}, {Name: "documents:read"}},
+			permission: "documents:read",
+			want:       false,
+		},
+		{
+			name:       "unrelated deny",
+			grants:     []Grant{{Name: "documents:*"}, {Name: "documents:delete", Deny: true}},
+			permission: "documents:read",
+			want:       true,
+		},
+		{
+			name:       "deny only",
+			grants:     []Grant{{Name: "documents:read", Deny: true}},
+			permission: "documents:read",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.grants, tt.permission); got != tt.want {
+				t.Fatalf("Allowed(%v, %q) = %v, want %v", tt.grants, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+var comparisonOperators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// operators are matched longest first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(source); {
+		c := rune(source[pos])
+
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+
+		case c == '"' || c == '\'':
+			end := pos + 1
+			var text strings.Builder
+
+			for ; end < len(source) && rune(source[end]) != c; end++ {
+				if source[end] == '\\' && end+1 < len(source) {
+					end++
+				}
+
+				text.WriteByte(source[end])
+			}
+
+			if end >= len(source) {
+				return nil, syntaxError(pos, "unterminated string")
+			}
+
+			tokens = append(tokens, token{kind: tokenString, value: text.String(), pos: pos})
+			pos = end + 1
+
+		case unicode.IsDigit(c) || (c == '-' && pos+1 < len(source) && unicode.IsDigit(rune(source[pos+1]))):
+			end := pos + 1
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+
+			number, err := strconv.ParseFloat(source[pos:end], 64)
+			if err != nil {
+				return nil, syntaxError(pos, "invalid number %q", source[pos:end])
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, value: number, pos: pos})
+			pos = end
+
+		case unicode.IsLetter(c) || c == '_':
+			end := pos + 1
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+
+		default:
+			matched := false
+
+			for _, operator := range operators {
+				if strings.HasPrefix(source[pos:], operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+					pos += len(operator)
+					matched = true
+
+					break
+				}
+			}
+
+			if !matched {
+				return nil, syntaxError(pos, "unexpected character %q", c)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// parser is a recursive descent parser of the grammar
+//
+//	or         = and { "||" and }
+//	and        = not { "&&" not }
+//	not        = "!" not | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) operand ]
+//	operand    = literal | path | list | "(" or ")"
+//	path       = ident { "." ident }
+//	list       = "[" [ or { "," or } ] "]"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) accept(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(operator string) error {
+	if !p.accept(operator) {
+		return syntaxError(p.peek().pos, "expected %q", operator)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{operator: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{operator: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+
+	var operator string
+	switch {
+	case t.kind == tokenOperator && comparisonOperators[t.text]:
+		operator = t.text
+	case t.kind == tokenIdent && t.text == "in":
+		operator = "in"
+	default:
+		return left, nil
+	}
+
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return binaryNode{operator: operator, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return literalNode{value: t.value}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		case "in":
+			return nil, syntaxError(t.pos, "unexpected \"in\"")
+		}
+
+		if _, ok := roots[t.text]; !ok {
+			return nil, syntaxError(t.pos, "unknown attribute root %q", t.text)
+		}
+
+		path := pathNode{t.text}
+		for p.accept(".") {
+			field := p.next()
+			if field.kind != tokenIdent {
+				return nil, syntaxError(field.pos, "expected attribute name")
+			}
+
+			path = append(path, field.text)
+		}
+
+		return path, nil
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return inner, nil
+
+		case "[":
+			var list listNode
+
+			if p.accept("]") {
+				return list, nil
+			}
+
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+
+				list = append(list, item)
+
+				if p.accept("]") {
+					return list, nil
+				}
+
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, syntaxError(t.pos, "unexpected end of expression")
+	}
+
+	return nil, syntaxError(t.pos, "unexpected %q", t.text)
+}
+
+func syntaxError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrorSyntax, fmt.Sprintf(format, args...), pos)
+}
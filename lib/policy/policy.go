@@ -0,0 +1,333 @@
+// Package policy implements the condition language of authorization policies.
+//
+// A condition is a boolean expression over the attributes of the subject (the user asking),
+// the resource and the environment, e.g.
+//
+//	subject.tenant == resource.tenant && "reviewer" in subject.roles
+//	env.hour >= 9 && env.hour < 17
+//
+// Supported are the literals "string", 'string', numbers, true, false and null, lists [a, b],
+// the comparisons == != < <= > >=, list membership with in, and the boolean operators ! && ||.
+// Missing attributes evaluate to null. Strings which hold numbers compare as numbers
+// against numbers, since attributes are often passed as strings.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	RootSubject     = "subject"
+	RootResource    = "resource"
+	RootEnvironment = "env"
+)
+
+var roots = map[string]struct{}{
+	RootSubject:     {},
+	RootResource:    {},
+	RootEnvironment: {},
+}
+
+var (
+	ErrorSyntax     = errors.New("policy syntax error")
+	ErrorType       = errors.New("policy type error")
+	ErrorNotBoolean = errors.New("policy condition is not boolean")
+)
+
+// Input holds the attributes a condition is evaluated against, keyed by root.
+// Attribute values are strings, numbers, bools, nil, lists ([]string or []interface{})
+// or nested maps (map[string]string or map[string]interface{}).
+type Input map[string]map[string]interface{}
+
+// Expression is a compiled condition, it is safe for concurrent use.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the condition.
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, syntaxError(t.pos, "unexpected %q", t.text)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the condition against the input. Conditions which don't evaluate to a bool fail.
+func (e *Expression) Evaluate(input Input) (bool, error) {
+	value, err := e.root.evaluate(input)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, ErrorNotBoolean
+	}
+
+	return result, nil
+}
+
+type node interface {
+	evaluate(input Input) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) evaluate(Input) (interface{}, error) {
+	return n.value, nil
+}
+
+type pathNode []string
+
+func (n pathNode) evaluate(input Input) (interface{}, error) {
+	var value interface{} = input[n[0]]
+
+	for _, field := range n[1:] {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			value = container[field]
+		case map[string]string:
+			if v, ok := container[field]; ok {
+				value = v
+			} else {
+				value = nil
+			}
+		default:
+			return nil, nil
+		}
+	}
+
+	return value, nil
+}
+
+type listNode []node
+
+func (n listNode) evaluate(input Input) (interface{}, error) {
+	values := make([]interface{}, 0, len(n))
+
+	for _, item := range n {
+		value, err := item.evaluate(input)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) evaluate(input Input) (interface{}, error) {
+	value, err := n.operand.evaluate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%w: ! needs a bool", ErrorType)
+	}
+
+	return !b, nil
+}
+
+type binaryNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n binaryNode) evaluate(input Input) (interface{}, error) {
+	left, err := n.left.evaluate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.operator == "&&" || n.operator == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s needs bools", ErrorType, n.operator)
+		}
+
+		// Short-circuit, the right side is not evaluated if the left decides.
+		if (n.operator == "&&" && !l) || (n.operator == "||" && l) {
+			return l, nil
+		}
+
+		right, err := n.right.evaluate(input)
+		if err != nil {
+			return nil, err
+		}
+
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s needs bools", ErrorType, n.operator)
+		}
+
+		return r, nil
+	}
+
+	right, err := n.right.evaluate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		// Ordering against null or between unrelated types never holds.
+		return false, nil
+	}
+
+	switch n.operator {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+
+	ab, aok := a.(bool)
+	bb, bok := b.(bool)
+
+	return aok && bok && ab == bb
+}
+
+// compare orders numbers and strings. A string is compared as a number if the other side is one.
+func compare(a interface{}, b interface{}) (int, bool) {
+	an, aNumber := number(a)
+	bn, bNumber := number(b)
+
+	if aNumber || bNumber {
+		if !aNumber {
+			an, aNumber = parseNumber(a)
+		}
+
+		if !bNumber {
+			bn, bNumber = parseNumber(b)
+		}
+
+		if !aNumber || !bNumber {
+			return 0, false
+		}
+
+		switch {
+		case an < bn:
+			return -1, true
+		case an > bn:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if !aok || !bok {
+		return 0, false
+	}
+
+	switch {
+	case as < bs:
+		return -1, true
+	case as > bs:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+// contains reports whether the list holds the value. Anything but a list holds nothing.
+func contains(list interface{}, value interface{}) bool {
+	switch items := list.(type) {
+	case []interface{}:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	case []string:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+func parseNumber(value interface{}) (float64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+
+	return n, err == nil
+}
@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "empty", source: ""},
+		{name: "unterminated string", source: `subject.name == "alice`},
+		{name: "unknown root", source: `user.name == "alice"`},
+		{name: "unknown character", source: `subject.age # 3`},
+		{name: "missing operand", source: `subject.age >=`},
+		{name: "missing closing parenthesis", source: `(subject.age > 3`},
+		{name: "missing closing bracket", source: `subject.role in ["a", "b"`},
+		{name: "missing comma", source: `subject.role in ["a" "b"]`},
+		{name: "trailing tokens", source: `subject.age > 3 subject.age`},
+		{name: "chained comparison", source: `1 < subject.age < 3`},
+		{name: "in without left side", source: `in subject.roles`},
+		{name: "path without field", source: `subject. == 1`},
+		{name: "dangling operator", source: `true &&`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if !errors.Is(err, ErrorSyntax) {
+				t.Fatalf("Compile(%q) = %v, want ErrorSyntax", tt.source, err)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	input := Input{
+		RootSubject: {
+			"id":       "u1",
+			"tenant":   "acme",
+			"age":      "42",
+			"level":    3,
+			"verified": true,
+			"roles":    []string{"editor", "reviewer"},
+			"address":  map[string]string{"country": "LT"},
+		},
+		RootResource: {
+			"tenant": "acme",
+			"owner":  "u2",
+			"tags":   []interface{}{"draft", 7.0},
+		},
+		RootEnvironment: {
+			"hour": 10,
+		},
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: `true`, want: true},
+		{source: `!true`, want: false},
+		{source: `subject.tenant == resource.tenant`, want: true},
+		{source: `subject.id == resource.owner`, want: false},
+		{source: `subject.id != resource.owner`, want: true},
+		{source: `'reviewer' in subject.roles`, want: true},
+		{source: `"admin" in subject.roles`, want: false},
+		{source: `"draft" in resource.tags`, want: true},
+		{source: `7 in resource.tags`, want: true},
+		{source: `subject.tenant in ["acme", "globex"]`, want: true},
+		{source: `subject.tenant in []`, want: false},
+		{source: `"editor" in subject.tenant`, want: false},
+		{source: `subject.address.country == "LT"`, want: true},
+		{source: `subject.address.city == null`, want: true},
+		{source: `subject.missing == null`, want: true},
+		{source: `subject.missing.field == null`, want: true},
+		{source: `subject.missing != "x"`, want: true},
+		{source: `subject.verified == true`, want: true},
+		{source: `subject.verified == "true"`, want: false},
+		{source: `subject.age > 40`, want: true},
+		{source: `subject.age == 42`, want: true},
+		{source: `subject.level >= 3 && subject.level < 4`, want: true},
+		{source: `subject.level <= -1`, want: false},
+		{source: `subject.missing < 5`, want: false},
+		{source: `subject.tenant > 5`, want: false},
+		{source: `"b" > "a"`, want: true},
+		{source: `env.hour >= 9 && env.hour < 17`, want: true},
+		{source: `env.hour < 9 || env.hour >= 17`, want: false},
+		{source: `!(env.hour < 9) && (subject.id == "u1" || false)`, want: true},
+		{source: `false && subject.tenant`, want: false},
+		{source: `true || subject.tenant`, want: true},
+		{source: `'it\'s' == "it's"`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expression, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile(%q) = %v", tt.source, err)
+			}
+
+			got, err := expression.Evaluate(input)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) = %v", tt.source, err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Evaluate(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr error
+	}{
+		{source: `subject.tenant`, wantErr: ErrorNotBoolean},
+		{source: `1`, wantErr: ErrorNotBoolean},
+		{source: `!subject.tenant`, wantErr: ErrorType},
+		{source: `subject.tenant && true`, wantErr: ErrorType},
+		{source: `true && subject.tenant`, wantErr: ErrorType},
+		{source: `false || 1`, wantErr: ErrorType},
+	}
+
+	input := Input{RootSubject: {"tenant": "acme"}}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expression, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile(%q) = %v", tt.source, err)
+			}
+
+			if _, err := expression.Evaluate(input); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Evaluate(%q) = %v, want %v", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package recoverycode
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"strings"
+)
+
+// alphabet leaves out characters which are easily confused when written down (0/o, 1/l/i).
+const (
+	alphabet   = "abcdefghjkmnpqrstuvwxyz23456789"
+	groupSize  = 5
+	groupCount = 2
+)
+
+// Generate returns count random recovery codes formatted as "xxxxx-xxxxx".
+func Generate(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		code, err := generate()
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// Normalize brings a code entered by a user to the form it was digested in.
+// Case, whitespace and group separators are ignored.
+func Normalize(code string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(code) {
+		if r == '-' || r == ' ' || r == '\t' {
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Digest returns the keyed SHA-256 digest of the normalized code, which is what gets stored.
+// Codes are random enough that a slow password hash isn't needed, and equal codes have
+// equal digests, so a code can be looked up by its digest.
+func Digest(key []byte, code string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(Normalize(code)))
+
+	return mac.Sum(nil)
+}
+
+func generate() (string, error) {
+	var b strings.Builder
+
+	max := big.NewInt(int64(len(alphabet)))
+
+	for i := 0; i < groupSize*groupCount; i++ {
+		if i > 0 && i%groupSize == 0 {
+			b.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteByte(alphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,84 @@
+package recoverycode
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{1}, 32)
+
+func TestGenerate(t *testing.T) {
+	codes, err := Generate(10)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+
+	if len(codes) != 10 {
+		t.Fatalf("Generate() returned %d codes, want 10", len(codes))
+	}
+
+	format := regexp.MustCompile(`^[` + alphabet + `]{5}-[` + alphabet + `]{5}$`)
+	seen := make(map[string]bool, len(codes))
+
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Fatalf("code %q doesn't match %s", code, format)
+		}
+
+		if seen[code] {
+			t.Fatalf("code %q generated twice", code)
+		}
+
+		seen[code] = true
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "abcde-fghjk", want: "abcdefghjk"},
+		{code: "ABCDE-FGHJK", want: "abcdefghjk"},
+		{code: " abcde fghjk\t", want: "abcdefghjk"},
+		{code: "abcdefghjk", want: "abcdefghjk"},
+		{code: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := Normalize(tt.code); got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	digest := Digest(testKey, "abcde-fghjk")
+
+	if len(digest) != 32 {
+		t.Fatalf("Digest() has %d bytes, want 32", len(digest))
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		code  string
+		equal bool
+	}{
+		{name: "same code", key: testKey, code: "abcde-fghjk", equal: true},
+		{name: "entered differently", key: testKey, code: " ABCDE FGHJK ", equal: true},
+		{name: "other code", key: testKey, code: "abcde-fghjm", equal: false},
+		{name: "other key", key: bytes.Repeat([]byte{2}, 32), code: "abcde-fghjk", equal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bytes.Equal(Digest(tt.key, tt.code), digest); got != tt.equal {
+				t.Fatalf("digests equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
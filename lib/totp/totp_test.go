@@ -0,0 +1,142 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 test key of RFC 6238, "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC 6238 test vectors are 8 digits long, the codes here are their last 6 digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range rfcVectors {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatalf("Code() = %v", err)
+			}
+
+			if got != tt.code {
+				t.Fatalf("Code() at %d = %s, want %s", tt.unix, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range rfcVectors {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := Validate(tt.code, rfcSecret, time.Unix(tt.unix, 0))
+			if !ok {
+				t.Fatalf("Validate() rejected the code at %d", tt.unix)
+			}
+
+			if want := tt.unix / period; step != want {
+				t.Fatalf("Validate() step = %d, want %d", step, want)
+			}
+		})
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	const unix = 1234567890
+
+	code, err := Code(rfcSecret, time.Unix(unix, 0))
+	if err != nil {
+		t.Fatalf("Code() = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{name: "same step", offset: 0, want: true},
+		{name: "one step later", offset: period * time.Second, want: true},
+		{name: "one step earlier", offset: -period * time.Second, want: true},
+		{name: "two steps later", offset: 2 * period * time.Second, want: false},
+		{name: "two steps earlier", offset: -2 * period * time.Second, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(code, rfcSecret, time.Unix(unix, 0).Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("Validate() = %v, want %v", ok, tt.want)
+			}
+
+			if ok && step != unix/period {
+				t.Fatalf("Validate() step = %d, want the step of the code %d", step, unix/period)
+			}
+		})
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		code   string
+		secret string
+	}{
+		{name: "wrong code", code: "123456", secret: rfcSecret},
+		{name: "too short", code: "28708", secret: rfcSecret},
+		{name: "too long", code: "2870820", secret: rfcSecret},
+		{name: "eight digit code", code: "94287082", secret: rfcSecret},
+		{name: "invalid secret", code: "287082", secret: "not base32!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.code, tt.secret, at); ok {
+				t.Fatalf("Validate(%q) accepted the code", tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateLowercaseSecret(t *testing.T) {
+	if _, ok := Validate("287082", strings.ToLower(rfcSecret), time.Unix(59, 0)); !ok {
+		t.Fatal("Validate() rejected the code of a lowercase secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() = %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+
+	if len(key) != secretSize {
+		t.Fatalf("secret has %d bytes, want %d", len(key), secretSize)
+	}
+
+	code, err := Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("Code() = %v", err)
+	}
+
+	if _, ok := Validate(code, secret, time.Now()); !ok {
+		t.Fatal("Validate() rejected the current code of a generated secret")
+	}
+}